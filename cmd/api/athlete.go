@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/adiazny/strong/internal/pkg/strava"
+)
+
+// stravaAthleteHandler shows which Strava account is connected along with its
+// activity totals.
+func (app *application) stravaAthleteHandler(w http.ResponseWriter, r *http.Request) {
+	provider := app.stravaProvider.Load()
+	if provider == nil {
+		http.Error(w, "strava is not connected", http.StatusServiceUnavailable)
+		return
+	}
+
+	athlete, err := provider.GetAthlete(r.Context())
+	if err != nil {
+		app.log.Printf("error getting strava athlete %v", err)
+		http.Error(w, "error getting strava athlete", http.StatusBadGateway)
+		return
+	}
+
+	stats, err := provider.GetAthleteStats(r.Context(), athlete.ID)
+	if err != nil {
+		app.log.Printf("error getting strava athlete stats %v", err)
+		http.Error(w, "error getting strava athlete stats", http.StatusBadGateway)
+		return
+	}
+
+	response := struct {
+		Athlete strava.Athlete      `json:"athlete"`
+		Name    string              `json:"name"`
+		Unit    string              `json:"unit"`
+		Stats   strava.AthleteStats `json:"stats"`
+	}{
+		Athlete: athlete,
+		Name:    athlete.Name(),
+		Unit:    athlete.WeightUnit().Symbol(),
+		Stats:   stats,
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "error marshling athlete to json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
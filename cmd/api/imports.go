@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/adiazny/strong/internal/pkg/data"
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+const (
+	maxImportBytes  = 32 << 20
+	importFormField = "file"
+)
+
+type importResponse struct {
+	data.Import
+	// Sync is "started" or "skipped" when ?sync=true asked for a sync.
+	Sync string `json:"sync,omitempty"`
+}
+
+// createImportHandler accepts a Strong export as a multipart form file named
+// "file" or as a raw text/csv body, for example from an iOS Shortcut. The
+// export is read leniently and stored, and unreadable rows are returned as
+// warnings. With ?sync=true and http in -source a sync is started.
+func (app *application) createImportHandler(w http.ResponseWriter, r *http.Request) {
+	syncRequested := r.URL.Query().Get("sync") == "true"
+
+	if syncRequested && !app.config.sources[sourceHTTP] {
+		http.Error(w, "uploads are only synced when -source includes http", http.StatusConflict)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	name, body, err := readImport(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesErr):
+			http.Error(w, "strong export is too large", http.StatusRequestEntityTooLarge)
+		case errors.Is(err, errUnsupportedImportType):
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+
+		return
+	}
+
+	workouts, warnings, err := strong.ProcessLenient(bytes.NewReader(body))
+	if err != nil {
+		writeImportJSON(w, http.StatusUnprocessableEntity, map[string]any{"error": err.Error(), "warnings": warnings})
+		return
+	}
+
+	imp, err := app.imports.Save(data.Import{Name: name, Workouts: len(workouts), Warnings: warnings}, body)
+	if err != nil {
+		app.log.Printf("error saving import %v", err)
+		http.Error(w, "error saving import", http.StatusInternalServerError)
+		return
+	}
+
+	app.log.Printf("received strong export %s with %d workouts and %d warnings", imp.ID, imp.Workouts, len(imp.Warnings))
+
+	response := importResponse{Import: imp}
+
+	if syncRequested {
+		response.Sync = "skipped"
+
+		if app.triggerSync() {
+			response.Sync = "started"
+		}
+	}
+
+	writeImportJSON(w, http.StatusCreated, response)
+}
+
+// validBearerToken compares the request's bearer token with token in
+// constant time. An empty token accepts nothing.
+func validBearerToken(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+var errUnsupportedImportType = errors.New("strong exports must be sent as multipart/form-data or text/csv")
+
+// readImport returns the uploaded file name, if known, and its contents.
+func readImport(r *http.Request) (string, []byte, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return "", nil, errUnsupportedImportType
+	}
+
+	switch mediaType {
+	case "multipart/form-data":
+		file, header, err := r.FormFile(importFormField)
+		if err != nil {
+			return "", nil, err
+		}
+
+		defer file.Close()
+
+		body, err := io.ReadAll(file)
+
+		return header.Filename, body, err
+	case "text/csv", "application/csv":
+		body, err := io.ReadAll(r.Body)
+
+		return "", body, err
+	default:
+		return "", nil, errUnsupportedImportType
+	}
+}
+
+func writeImportJSON(w http.ResponseWriter, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "error marshling import to json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/adiazny/strong/internal/pkg/ledger"
+)
+
+// ledgerHandler lists the sync ledger for auditing, optionally limited to one
+// destination with ?destination=strava.
+func (app *application) ledgerHandler(w http.ResponseWriter, r *http.Request) {
+	destination := r.URL.Query().Get("destination")
+
+	entries := make([]ledger.Entry, 0)
+
+	for _, entry := range app.ledger.Entries() {
+		if destination == "" || entry.Destination == destination {
+			entries = append(entries, entry)
+		}
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		http.Error(w, "error marshling ledger to json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/adiazny/strong/internal/pkg/data"
+)
+
+// syncPlanHandler imports the latest Strong export and returns the Strava
+// activities a sync would create, update or delete, without applying them.
+func (app *application) syncPlanHandler(w http.ResponseWriter, r *http.Request) {
+	stravaProvider := app.stravaProvider.Load()
+
+	if stravaProvider == nil {
+		http.Error(w, "strava is not connected yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	workouts, err := app.latestWorkouts(r.Context())
+	if errors.Is(err, errSourceNotConnected) {
+		http.Error(w, "google drive is not connected yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	if errors.Is(err, data.ErrNotModified) {
+		http.Error(w, "no strong export has been found yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err != nil {
+		app.log.Printf("error importing workouts for sync plan %v", err)
+		http.Error(w, "error importing workouts", http.StatusBadGateway)
+		return
+	}
+
+	plan, err := stravaProvider.Plan(r.Context(), workouts)
+	if err != nil {
+		app.log.Printf("error planning strava sync %v", err)
+		http.Error(w, "error planning strava sync", http.StatusBadGateway)
+		return
+	}
+
+	data, err := json.Marshal(plan)
+	if err != nil {
+		http.Error(w, "error marshling sync plan to json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -4,28 +4,51 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/adiazny/strong/internal/pkg/auth"
 	"github.com/adiazny/strong/internal/pkg/web"
 )
 
+// redirectHandler completes an authorization attempt. The state must be one
+// issued for an attempt started here, not expired and not used before;
+// otherwise the callback may be forged and the code is not exchanged.
 func (app *application) redirectHandler(w http.ResponseWriter, r *http.Request) {
-	app.log.Printf("redirect handler triggered for %s", r.URL.String())
+	app.log.Printf("redirect handler triggered for %s", r.URL.Path)
 
 	code, state, err := web.ParseRequest(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	switch state {
-	case "gdrive-state":
-		_, err := app.gdriveAuthProvider.Exchange(r.Context(), code)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-	case "strava-state":
-		_, err := app.stravaAuthProvider.Exchange(r.Context(), code)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	service, err := app.oauthStates.Consume(state)
+	if err != nil {
+		app.log.Printf("error rejecting oauth redirect %v", err)
+		http.Error(w, "invalid or expired oauth state, start the authorization again", http.StatusBadRequest)
+		return
+	}
+
+	var provider *auth.Provider
+
+	switch service {
+	case auth.GDriveService:
+		provider = app.gdriveAuthProvider
+	case auth.StravaService:
+		provider = app.stravaAuthProvider
+	}
+
+	if provider == nil {
+		http.Error(w, "google drive is not a workout source", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := provider.Exchange(r.Context(), code); err != nil {
+		// The state is used up, so the attempt has to start over.
+		if url, err := provider.AuthCodeURL(); err == nil {
+			app.log.Println(url)
 		}
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	fmt.Fprint(w, "redirect successful")
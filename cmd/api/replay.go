@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/data"
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+// replayChecksumLength is how much of the checksum -archive-list shows,
+// enough to pass to -replay.
+const replayChecksumLength = 12
+
+// replay parses an archived export again and logs the Strava sync plan for
+// it, to reproduce a past sync with the current parser and matcher. Nothing
+// is applied, exported or committed.
+func (app *application) replay(ctx context.Context, ref string) error {
+	entry, body, err := app.archive.Get(ref)
+	if err != nil {
+		return fmt.Errorf("error loading archived export: %w", err)
+	}
+
+	app.log.Printf("replaying %s, %s from %s fetched %s", entry.Checksum, entry.Name, entry.Location, entry.FetchedAt.Format(time.RFC3339))
+
+	workouts, warnings, err := processArchived(entry, body)
+	if err != nil {
+		return fmt.Errorf("error processing archived export: %w", err)
+	}
+
+	for _, warning := range warnings {
+		app.log.Printf("unreadable row: %s", warning)
+	}
+
+	app.log.Printf("parsed %d workouts, skipped %d unreadable rows", len(workouts), len(warnings))
+
+	stravaProvider, err := app.syncStravaProvider(ctx)
+	if err != nil {
+		return err
+	}
+
+	plan, err := stravaProvider.Plan(ctx, workouts)
+	if err != nil {
+		return fmt.Errorf("error planning strava sync: %w", err)
+	}
+
+	app.log.Printf("replayed strava sync plan: %s", plan)
+
+	return nil
+}
+
+// listArchive writes the archive index, oldest fetch first.
+func listArchive(w io.Writer, archive *data.ExportArchive) error {
+	entries, err := archive.Entries()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "SHA256\tFETCHED\tSIZE\tNAME\tLOCATION")
+
+	for _, entry := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n",
+			entry.Checksum[:min(replayChecksumLength, len(entry.Checksum))],
+			entry.FetchedAt.Local().Format(time.RFC3339), entry.Size, entry.Name, entry.Location)
+	}
+
+	return tw.Flush()
+}
+
+// diffArchived writes the workouts changed between two archived exports,
+// given as "old,new" references.
+func diffArchived(w io.Writer, archive *data.ExportArchive, refs string) error {
+	before, after, ok := strings.Cut(refs, ",")
+	if !ok {
+		return fmt.Errorf("error -diff needs two archived exports, old,new: %q", refs)
+	}
+
+	beforeWorkouts, err := archivedWorkouts(archive, strings.TrimSpace(before))
+	if err != nil {
+		return err
+	}
+
+	afterWorkouts, err := archivedWorkouts(archive, strings.TrimSpace(after))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprint(w, strong.Diff(beforeWorkouts, afterWorkouts))
+
+	return err
+}
+
+func archivedWorkouts(archive *data.ExportArchive, ref string) ([]strong.Workout, error) {
+	entry, body, err := archive.Get(ref)
+	if err != nil {
+		return nil, fmt.Errorf("error loading archived export %s: %w", ref, err)
+	}
+
+	workouts, _, err := processArchived(entry, body)
+	if err != nil {
+		return nil, fmt.Errorf("error processing archived export %s: %w", ref, err)
+	}
+
+	return workouts, nil
+}
+
+// processArchived parses an archived export the way it was parsed when
+// fetched, skipping unreadable rows only if they were skipped then.
+func processArchived(entry data.ArchiveEntry, body []byte) ([]strong.Workout, []strong.Warning, error) {
+	if entry.Lenient {
+		return strong.ProcessLenient(bytes.NewReader(body))
+	}
+
+	workouts, err := strong.Process(bytes.NewReader(body))
+
+	return workouts, nil, err
+}
@@ -12,5 +12,32 @@ func (app *application) routes() *httprouter.Router {
 	router.HandlerFunc(http.MethodGet, "/v1/health", app.healthHandler)
 	router.HandlerFunc(http.MethodGet, "/v1/redirect", app.redirectHandler)
 
+	// The port is public for Strava webhooks. Uploads can replace the sync
+	// source and the other routes read Drive and Strava or expose the
+	// athlete's profile, so they are only served with a token.
+	if app.config.importToken != "" {
+		router.HandlerFunc(http.MethodGet, "/v1/ledger", app.requireToken(app.ledgerHandler))
+		router.HandlerFunc(http.MethodGet, "/v1/sync/plan", app.requireToken(app.syncPlanHandler))
+		router.HandlerFunc(http.MethodPost, "/v1/imports", app.requireToken(app.createImportHandler))
+		router.HandlerFunc(http.MethodGet, "/v1/strava/athlete", app.requireToken(app.stravaAthleteHandler))
+	}
+
+	router.HandlerFunc(http.MethodGet, "/v1/strava/webhook", app.stravaWebhookVerifyHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/strava/webhook", app.stravaWebhookEventHandler)
+
 	return router
 }
+
+// requireToken only passes on requests carrying the -import-token as a
+// bearer token.
+func (app *application) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !validBearerToken(r, app.config.importToken) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/adiazny/strong/internal/pkg/data"
+	"github.com/adiazny/strong/internal/pkg/gdrive"
+	"github.com/adiazny/strong/internal/pkg/s3"
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+var errSourceNotConnected = errors.New("workout source is not connected yet")
+
+// driveSource is the Google Drive data.Source. The Drive client only exists
+// once authorized, so it is looked up on every Open. Sync runs wait for
+// authorization; with latest set, Open never waits and ignores the saved
+// state, for previews.
+type driveSource struct {
+	app    *application
+	latest bool
+}
+
+func (source driveSource) Open(ctx context.Context) (io.ReadCloser, data.Metadata, error) {
+	if !source.latest {
+		provider, err := source.app.gdriveProvider(ctx)
+		if err != nil {
+			return nil, data.Metadata{}, err
+		}
+
+		return provider.Open(ctx)
+	}
+
+	provider := source.app.driveProvider.Load()
+	if provider == nil {
+		return nil, data.Metadata{}, errSourceNotConnected
+	}
+
+	fileBytes, err := provider.Latest(ctx)
+	if err != nil {
+		return nil, data.Metadata{}, err
+	}
+
+	return io.NopCloser(bytes.NewReader(fileBytes)), data.Metadata{Name: "google drive"}, nil
+}
+
+func (source driveSource) Commit(ctx context.Context) error {
+	provider := source.app.driveProvider.Load()
+	if source.latest || provider == nil {
+		return nil
+	}
+
+	if err := provider.Commit(ctx); err != nil {
+		return fmt.Errorf("error saving gdrive state: %w", err)
+	}
+
+	return nil
+}
+
+// configuredSources combines the -source places, in the order Drive, S3,
+// directory, uploads, so later uploads win over older exports. Sync runs
+// archive what they import locally and to -s3-archive-bucket.
+func (app *application) configuredSources(latest bool) *data.Multi {
+	var sources []data.Source
+
+	if app.config.sources[sourceGDrive] {
+		sources = append(sources, driveSource{app: app, latest: latest})
+	}
+
+	if app.s3Source != nil {
+		source := app.s3Source
+
+		// A fresh source has nothing committed, so its newest export is opened.
+		if latest {
+			source = &s3.Source{Client: source.Client, Bucket: source.Bucket, Prefix: source.Prefix, Pattern: source.Pattern}
+		}
+
+		sources = append(sources, source)
+	}
+
+	if app.config.sources[sourceDir] {
+		if latest {
+			sources = append(sources, app.watcher.Newest())
+		} else {
+			sources = append(sources, app.watcher)
+		}
+	}
+
+	if app.config.sources[sourceHTTP] {
+		sources = append(sources, app.imports)
+	}
+
+	multi := data.NewMulti(app.log, sources...)
+
+	if !latest {
+		for _, archiver := range app.archivers {
+			multi.ArchiveTo(archiver)
+		}
+	}
+
+	return multi
+}
+
+// importSource imports workouts from the configured sources. The returned
+// commit marks the import as handled once it has been synced. It returns
+// data.ErrNotModified when no source has anything new.
+func (app *application) importSource(ctx context.Context) ([]strong.Workout, func(ctx context.Context) error, error) {
+	if !app.config.gdriveAll {
+		workouts, err := app.sources.Import(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return workouts, app.sources.Commit, nil
+	}
+
+	driveProvider, err := app.gdriveProvider(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	workouts, err := app.importAllDrive(ctx, driveProvider)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return workouts, driveSource{app: app}.Commit, nil
+}
+
+// latestWorkouts imports the newest export of every source, whether or not
+// it was synced already. It never waits for authorization.
+func (app *application) latestWorkouts(ctx context.Context) ([]strong.Workout, error) {
+	return app.configuredSources(true).Import(ctx)
+}
+
+// importAllDrive merges every Drive export not processed yet, for
+// -gdrive-all.
+func (app *application) importAllDrive(ctx context.Context, driveProvider *gdrive.Provider) ([]strong.Workout, error) {
+	exports, err := driveProvider.ImportAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error importing gdrive files: %w", err)
+	}
+
+	parsed := make([][]strong.Workout, 0, len(exports))
+
+	for _, driveExport := range exports {
+		metadata := data.Metadata{Name: driveExport.Name, Location: "gdrive:" + driveExport.FileID}
+
+		for _, archiver := range app.archivers {
+			if err := archiver.Archive(ctx, metadata, driveExport.Data); err != nil {
+				return nil, fmt.Errorf("error archiving %s: %w", driveExport.Name, err)
+			}
+		}
+
+		workouts, err := strong.Process(bytes.NewReader(driveExport.Data))
+		if err != nil {
+			return nil, fmt.Errorf("error processing %s: %w", driveExport.Name, err)
+		}
+
+		parsed = append(parsed, workouts)
+	}
+
+	workouts := strong.Merge(parsed...)
+
+	app.log.Printf("merged %d workouts from %d strong exports", len(workouts), len(exports))
+
+	return workouts, nil
+}
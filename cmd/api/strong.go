@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"flag"
@@ -9,15 +8,26 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/adiazny/strong/internal/pkg/auth"
+	"github.com/adiazny/strong/internal/pkg/data"
+	"github.com/adiazny/strong/internal/pkg/export"
+	"github.com/adiazny/strong/internal/pkg/format"
 	"github.com/adiazny/strong/internal/pkg/gdrive"
+	"github.com/adiazny/strong/internal/pkg/ledger"
+	"github.com/adiazny/strong/internal/pkg/s3"
+	"github.com/adiazny/strong/internal/pkg/schedule"
 	"github.com/adiazny/strong/internal/pkg/store"
 	"github.com/adiazny/strong/internal/pkg/strava"
 	"github.com/adiazny/strong/internal/pkg/strong"
 	"google.golang.org/api/drive/v3"
-	"google.golang.org/api/option"
 )
 
 const version = "1.1.0"
@@ -26,20 +36,79 @@ const (
 	defaultAPIPort     = 5000
 	defaultPath        = "./strong.csv"
 	defaultRedirectURL = "http://localhost:4001/v1/redirect"
+	// Strong names repeated exports strong (1).csv, strong 2.csv and so on,
+	// so -gdrive-all matches every CSV unless -gdrive-pattern is set.
+	defaultGDriveAllPattern = "*.csv"
 
 	gdriveTokenPath = "gdrive/storage.json"
 	stravaTokenPath = "strava/storage.json"
+	stravaCachePath = "strava/activities.json"
+	ledgerPath      = "strong/ledger.json"
+	gdriveStatePath = "gdrive/state.json"
+	importsPath     = "strong/imports"
+	archivePath     = "strong/archive"
+
+	sourceGDrive = "gdrive"
+	sourceDir    = "dir"
+	sourceHTTP   = "http"
+	sourceS3     = "s3"
+
+	defaultSyncInterval = time.Hour
+	shutdownTimeout     = 30 * time.Second
 )
 
 type config struct {
-	port               int
-	path               string
-	stravaClientID     string
-	stravaClientSecret string
-	stravaRedirectURL  string
-	gdriveClientID     string
-	gdriveClientSecret string
-	gdriveRedirectURL  string
+	port                 int
+	path                 string
+	stravaClientID       string
+	stravaClientSecret   string
+	stravaRedirectURL    string
+	gdriveClientID       string
+	gdriveClientSecret   string
+	gdriveRedirectURL    string
+	gdriveFolderID       string
+	gdriveFolder         string
+	gdrivePattern        string
+	gdriveAll            bool
+	gdriveArchiveID      string
+	source               string
+	sources              map[string]bool
+	watchDir             string
+	importToken          string
+	s3Endpoint           string
+	s3Region             string
+	s3AccessKey          string
+	s3SecretKey          string
+	s3Bucket             string
+	s3Prefix             string
+	s3Pattern            string
+	s3ArchiveBucket      string
+	s3ArchivePrefix      string
+	archiveDir           string
+	archiveList          bool
+	diff                 string
+	replay               string
+	matcher              strava.Matcher
+	nameTemplate         string
+	descTemplate         string
+	activityRules        string
+	uploadFormat         string
+	weightUnit           string
+	exportFormat         string
+	exportDir            string
+	stravaVerifyToken    string
+	stravaSubscriptionID int64
+	activityPhotos       bool
+	interval             time.Duration
+	cron                 string
+	once                 bool
+	dryRun               bool
+	prune                bool
+	since                string
+	until                string
+	backfill             bool
+	maxUploads           int
+	pacing               time.Duration
 }
 
 type application struct {
@@ -47,11 +116,38 @@ type application struct {
 	log                *log.Logger
 	stravaAuthProvider *auth.Provider
 	gdriveAuthProvider *auth.Provider
+	oauthStates        *auth.StateStore
+	gdriveStore        *store.File
+	stravaStore        *store.File
+	activityCache      *strava.ActivityCache
+	ledger             *ledger.Ledger
+	driveState         *gdrive.StateFile
+	watcher            *data.Watcher
+	imports            *data.ImportStore
+	s3Source           *s3.Source
+	archive            *data.ExportArchive
+	archivers          []data.Archiver
+	sources            *data.Multi
+	formatter          *format.Formatter
+	rules              strava.ActivityRules
+	since              time.Time
+	until              time.Time
+	stravaProvider     atomic.Pointer[strava.Provider]
+	driveProvider      atomic.Pointer[gdrive.Provider]
+
+	// stravaAthleteID is the connected athlete, 0 until known.
+	stravaAthleteID atomic.Int64
+
+	// triggerSync starts a sync in the background unless one is running.
+	triggerSync func() bool
+
+	// Only touched by sync runs, which never overlap.
+	exportOptions export.Options
+	lastSynced    []strong.Workout
 }
 
 func main() {
 	log := log.New(os.Stdout, "", log.Ldate|log.Ltime)
-	ctx := context.Background()
 
 	var cfg config
 
@@ -63,8 +159,147 @@ func main() {
 	flag.StringVar(&cfg.gdriveClientID, "gdrive-client", os.Getenv("GDRIVE_CLIENT_ID"), "Google Drive API Client ID")
 	flag.StringVar(&cfg.gdriveClientSecret, "gdrive-secret", os.Getenv("GDRIVE_CLIENT_SECRET"), "Google Drive API Client Secret")
 	flag.StringVar(&cfg.gdriveRedirectURL, "gdrive-redirect", defaultRedirectURL, "Google Drive Redirect URL")
+	flag.StringVar(&cfg.gdriveFolderID, "gdrive-folder-id", "", "Google Drive folder ID to search for Strong exports")
+	flag.StringVar(&cfg.gdriveFolder, "gdrive-folder", "", "Google Drive folder path, such as Backups/Strong, to search for Strong exports")
+	flag.StringVar(&cfg.gdrivePattern, "gdrive-pattern", "", "Glob matching Strong export names in Google Drive, such as strong*.csv, defaults to the -path file name, or "+defaultGDriveAllPattern+" with -gdrive-all")
+	flag.StringVar(&cfg.source, "source", sourceGDrive, "Comma separated places Strong exports are read from and merged: gdrive, s3, dir and http uploads")
+	flag.StringVar(&cfg.watchDir, "watch-dir", "", "Directory watched for Strong exports with -source dir")
+	flag.StringVar(&cfg.importToken, "import-token", os.Getenv("STRONG_IMPORT_TOKEN"), "Bearer token requests to /v1/imports, /v1/ledger, /v1/sync/plan and /v1/strava/athlete must send, the endpoints are disabled without one")
+	flag.StringVar(&cfg.s3Endpoint, "s3-endpoint", os.Getenv("S3_ENDPOINT"), "URL of the S3 compatible server, such as http://nas.local:9000 for MinIO")
+	flag.StringVar(&cfg.s3Region, "s3-region", s3.DefaultRegion, "S3 region requests are signed for")
+	flag.StringVar(&cfg.s3AccessKey, "s3-access-key", os.Getenv("S3_ACCESS_KEY"), "S3 access key")
+	flag.StringVar(&cfg.s3SecretKey, "s3-secret-key", os.Getenv("S3_SECRET_KEY"), "S3 secret key")
+	flag.StringVar(&cfg.s3Bucket, "s3-bucket", "", "S3 bucket Strong exports are read from with -source s3")
+	flag.StringVar(&cfg.s3Prefix, "s3-prefix", "", "Key prefix, such as exports/, of Strong exports in -s3-bucket")
+	flag.StringVar(&cfg.s3Pattern, "s3-pattern", s3.DefaultPattern, "Glob matching Strong export names in -s3-bucket")
+	flag.StringVar(&cfg.s3ArchiveBucket, "s3-archive-bucket", "", "S3 bucket every imported Strong export is archived to, whatever its source")
+	flag.StringVar(&cfg.s3ArchivePrefix, "s3-archive-prefix", "", "Key prefix of exports archived to -s3-archive-bucket")
+	flag.StringVar(&cfg.archiveDir, "archive-dir", "", "Directory every imported Strong export is archived to by SHA-256, defaults to ~/"+archivePath)
+	flag.BoolVar(&cfg.archiveList, "archive-list", false, "List the archived Strong exports and exit")
+	flag.StringVar(&cfg.diff, "diff", "", "Print the workouts changed between two archived Strong exports, given as old,new references like -replay, and exit")
+	flag.StringVar(&cfg.replay, "replay", "", "Parse an archived Strong export, by SHA-256, unique prefix or latest, and log the Strava sync plan for it, then exit")
+	flag.BoolVar(&cfg.gdriveAll, "gdrive-all", false, "Import every Strong export in Google Drive matching -gdrive-pattern not yet processed, not only the latest")
+	flag.StringVar(&cfg.gdriveArchiveID, "gdrive-archive-folder-id", "", "Google Drive folder ID processed exports are moved to with -gdrive-all, needs full drive access")
+	flag.DurationVar(&cfg.matcher.Tolerance, "match-tolerance", strava.DefaultMatcher().Tolerance, "Maximum start time difference when matching workouts to Strava activities")
+	flag.Float64Var(&cfg.matcher.DurationTolerance, "match-duration-tolerance", strava.DefaultMatcher().DurationTolerance, "Maximum relative duration difference when matching workouts to Strava activities")
+	flag.BoolVar(&cfg.matcher.MatchTimezoneShifts, "match-timezones", strava.DefaultMatcher().MatchTimezoneShifts, "Match Strava activities offset from a workout by a timezone difference")
+	flag.StringVar(&cfg.nameTemplate, "name-template", "", "Path to a text/template file for Strava activity names")
+	flag.StringVar(&cfg.descTemplate, "description-template", "", "Path to a text/template file for Strava activity descriptions")
+	flag.StringVar(&cfg.activityRules, "activity-rules", "", "Path to a JSON file of per-program Strava activity rules")
+	flag.StringVar(&cfg.uploadFormat, "upload-format", "manual", "How workouts are sent to Strava: manual, fit or tcx")
+	flag.StringVar(&cfg.weightUnit, "weight-unit", "", "Unit weights are recorded in by the Strong app: lb or kg, defaults to the Strava athlete's measurement preference")
+	flag.StringVar(&cfg.exportFormat, "export-format", export.FormatTCX, "Format of exported workout files: tcx, gpx or fit")
+	flag.StringVar(&cfg.exportDir, "export-dir", "", "Directory to export workout files to before uploading")
+	flag.StringVar(&cfg.stravaVerifyToken, "strava-verify-token", os.Getenv("STRAVA_VERIFY_TOKEN"), "Strava webhook subscription verify token")
+	flag.Int64Var(&cfg.stravaSubscriptionID, "strava-subscription-id", 0, "Strava webhook subscription ID")
+	flag.BoolVar(&cfg.activityPhotos, "activity-photos", false, "Experimental: attach a PNG summary card to each Strava activity created. Uses an undocumented Strava endpoint that may reject it; failures are only logged")
+	flag.DurationVar(&cfg.interval, "interval", defaultSyncInterval, "Time between sync runs")
+	flag.StringVar(&cfg.cron, "cron", "", "Five field cron expression for sync runs, overrides -interval")
+	flag.BoolVar(&cfg.once, "once", false, "Run a single sync and exit")
+	flag.BoolVar(&cfg.dryRun, "dry-run", false, "Log the Strava activities a sync would create, update or delete without changing anything")
+	flag.BoolVar(&cfg.prune, "prune", false, "Delete Strava activities of uploaded workouts that were removed from Strong, through an undocumented Strava endpoint that may refuse it")
+	flag.StringVar(&cfg.since, "since", "", "Only sync workouts on or after this date, YYYY-MM-DD")
+	flag.StringVar(&cfg.until, "until", "", "Only sync workouts on or before this date, YYYY-MM-DD")
+	flag.BoolVar(&cfg.backfill, "backfill", false, "Upload the oldest workouts first as private activities hidden from the home feed")
+	flag.IntVar(&cfg.maxUploads, "max-uploads", 0, "Maximum Strava activities created per sync run, 0 for no limit")
+	flag.DurationVar(&cfg.pacing, "pacing", 0, "Time to wait between Strava writes")
 	flag.Parse()
 
+	if cfg.weightUnit != "" {
+		if _, err := parseWeightUnit(cfg.weightUnit); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// GPX is not offered since lifting workouts, which have no distance,
+	// can not be rendered as GPX.
+	switch cfg.uploadFormat {
+	case "manual", export.FormatFIT, export.FormatTCX:
+	default:
+		log.Fatalf("error unknown upload format %q", cfg.uploadFormat)
+	}
+
+	if _, err := path.Match(cfg.gdrivePattern, ""); err != nil {
+		log.Fatalf("error invalid -gdrive-pattern %q: %v", cfg.gdrivePattern, err)
+	}
+
+	if _, err := path.Match(cfg.s3Pattern, ""); err != nil {
+		log.Fatalf("error invalid -s3-pattern %q: %v", cfg.s3Pattern, err)
+	}
+
+	sources, err := parseSources(cfg.source)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg.sources = sources
+
+	if cfg.sources[sourceDir] && cfg.watchDir == "" {
+		log.Fatal("-source dir needs -watch-dir")
+	}
+
+	if cfg.sources[sourceHTTP] && cfg.importToken == "" {
+		log.Fatal("-source http needs -import-token")
+	}
+
+	if cfg.sources[sourceS3] && cfg.s3Bucket == "" {
+		log.Fatal("-source s3 needs -s3-bucket")
+	}
+
+	if cfg.gdriveAll && cfg.source != sourceGDrive {
+		log.Fatal("-gdrive-all needs -source gdrive only")
+	}
+
+	if cfg.gdriveAll && cfg.gdrivePattern == "" {
+		cfg.gdrivePattern = defaultGDriveAllPattern
+	}
+
+	if cfg.gdriveArchiveID != "" && !cfg.gdriveAll {
+		log.Fatal("-gdrive-archive-folder-id needs -gdrive-all")
+	}
+
+	// Exports already processed are not downloaded again, so workouts missing
+	// from the new exports are not known to be deleted.
+	if cfg.gdriveAll && cfg.prune {
+		log.Fatal("-prune can not be used with -gdrive-all")
+	}
+
+	// Uploads may be partial or date filtered exports, whose missing workouts
+	// were not removed from Strong.
+	if cfg.sources[sourceHTTP] && cfg.prune {
+		log.Fatal("-prune can not be used with -source http")
+	}
+
+	// Unchanged sources are only merged from memory, so after a restart the
+	// workouts of one may be missing and would be deleted.
+	if len(cfg.sources) > 1 && cfg.prune {
+		log.Fatal("-prune can not be used with more than one -source")
+	}
+
+	syncSchedule, err := parseSchedule(cfg.interval, cfg.cron)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	since, until, err := parseDateRange(cfg.since, cfg.until)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	formatter, err := format.Load(cfg.nameTemplate, cfg.descTemplate)
+	if err != nil {
+		log.Fatalf("error loading activity templates %v", err)
+	}
+
+	var rules strava.ActivityRules
+
+	if cfg.activityRules != "" {
+		rules, err = strava.LoadActivityRules(cfg.activityRules)
+		if err != nil {
+			log.Fatalf("error loading strava activity rules %v", err)
+		}
+	}
+
 	//========================================================================
 	// Create files
 
@@ -80,16 +315,108 @@ func main() {
 		os.Exit(1)
 	}
 
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("error finding home directory %v", err)
+	}
+
+	activityCache, err := strava.NewActivityCache(filepath.Join(homeDir, stravaCachePath))
+	if err != nil {
+		log.Fatalf("error creating strava activity cache %v", err)
+	}
+
+	syncLedger, err := ledger.Open(filepath.Join(homeDir, ledgerPath))
+	if err != nil {
+		log.Fatalf("error opening sync ledger %v", err)
+	}
+
+	driveState, err := gdrive.NewStateFile(filepath.Join(homeDir, gdriveStatePath))
+	if err != nil {
+		log.Fatalf("error creating gdrive state file %v", err)
+	}
+
+	imports, err := data.NewImportStore(filepath.Join(homeDir, importsPath))
+	if err != nil {
+		log.Fatalf("error creating import store %v", err)
+	}
+
+	archiveDir := cfg.archiveDir
+	if archiveDir == "" {
+		archiveDir = filepath.Join(homeDir, archivePath)
+	}
+
+	archive, err := data.NewExportArchive(archiveDir)
+	if err != nil {
+		log.Fatalf("error creating export archive %v", err)
+	}
+
+	if cfg.archiveList {
+		if err := listArchive(os.Stdout, archive); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if cfg.diff != "" {
+		if err := diffArchived(os.Stdout, archive, cfg.diff); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	archivers := []data.Archiver{archive}
+
 	//========================================================================
 	// Bootstrap OAuth Providers
 
-	gdriveAuthProvider, err := auth.NewProvider(auth.GDriveService, gdriveTokenPath, cfg.gdriveClientID, cfg.gdriveClientSecret, cfg.gdriveRedirectURL, gStore)
-	if err != nil {
-		log.Printf("error creating gdrive auth provider %v\n", err)
-		os.Exit(1)
+	// Both services redirect to the same handler, which tells them apart by
+	// the state each attempt was issued.
+	oauthStates := auth.NewStateStore(auth.DefaultStateTTL)
+
+	var gdriveAuthProvider *auth.Provider
+
+	if cfg.sources[sourceGDrive] {
+		gdriveAuthProvider, err = auth.NewProvider(auth.GDriveService, gdriveTokenPath, cfg.gdriveClientID, cfg.gdriveClientSecret, cfg.gdriveRedirectURL, gStore, oauthStates)
+		if err != nil {
+			log.Printf("error creating gdrive auth provider %v\n", err)
+			os.Exit(1)
+		}
+
+		// Archiving moves files, which read only access does not allow.
+		if cfg.gdriveArchiveID != "" {
+			gdriveAuthProvider.Config.Scopes = []string{drive.DriveScope}
+		}
 	}
 
-	stravaAuthProvider, err := auth.NewProvider(auth.StravaService, stravaTokenPath, cfg.stravaClientID, cfg.stravaClientSecret, cfg.stravaRedirectURL, stravaStore)
+	var watcher *data.Watcher
+
+	if cfg.sources[sourceDir] {
+		watcher, err = data.NewWatcher(log, cfg.watchDir)
+		if err != nil {
+			log.Fatalf("error creating directory watcher %v", err)
+		}
+	}
+
+	var s3Source *s3.Source
+
+	if cfg.sources[sourceS3] || cfg.s3ArchiveBucket != "" {
+		s3Client, err := s3.NewClient(cfg.s3Endpoint, cfg.s3AccessKey, cfg.s3SecretKey, s3.WithRegion(cfg.s3Region))
+		if err != nil {
+			log.Fatalf("error creating s3 client %v", err)
+		}
+
+		if cfg.sources[sourceS3] {
+			s3Source = &s3.Source{Client: s3Client, Bucket: cfg.s3Bucket, Prefix: cfg.s3Prefix, Pattern: cfg.s3Pattern}
+		}
+
+		if cfg.s3ArchiveBucket != "" {
+			archivers = append(archivers, &s3.Archiver{Client: s3Client, Bucket: cfg.s3ArchiveBucket, Prefix: cfg.s3ArchivePrefix})
+		}
+	}
+
+	stravaAuthProvider, err := auth.NewProvider(auth.StravaService, stravaTokenPath, cfg.stravaClientID, cfg.stravaClientSecret, cfg.stravaRedirectURL, stravaStore, oauthStates)
 	if err != nil {
 		log.Printf("error creating strava auth provider %v\n", err)
 		os.Exit(1)
@@ -103,6 +430,21 @@ func main() {
 		log:                log,
 		stravaAuthProvider: stravaAuthProvider,
 		gdriveAuthProvider: gdriveAuthProvider,
+		oauthStates:        oauthStates,
+		gdriveStore:        gStore,
+		stravaStore:        stravaStore,
+		activityCache:      activityCache,
+		ledger:             syncLedger,
+		driveState:         driveState,
+		watcher:            watcher,
+		imports:            imports,
+		s3Source:           s3Source,
+		archive:            archive,
+		archivers:          archivers,
+		formatter:          formatter,
+		rules:              rules,
+		since:              since,
+		until:              until,
 	}
 
 	srv := &http.Server{
@@ -124,72 +466,145 @@ func main() {
 	}()
 
 	//========================================================================
-	// Google Drive Flow
-
-	token, err := gdriveAuthProvider.Storage.GetToken()
-	if err != nil || !token.Valid() {
-		gdriveURL := gdriveAuthProvider.AuthCodeURL("gdrive-state")
-		log.Println(gdriveURL)
+	// Sync Flow
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	app.sources = app.configuredSources(false)
+
+	scheduler := schedule.New(log, syncSchedule, app.runSync)
+	app.triggerSync = func() bool { return scheduler.Trigger(ctx) }
+
+	switch {
+	case cfg.replay != "":
+		err = app.replay(ctx, cfg.replay)
+	case cfg.once:
+		err = scheduler.RunOnce(ctx)
+	default:
+		// New exports in the watched directory sync straight away.
+		if watcher != nil {
+			go func() {
+				if err := watcher.Run(ctx, scheduler.Trigger); err != nil && !errors.Is(err, context.Canceled) {
+					log.Printf("error watching %s, new exports are only synced on schedule %v", cfg.watchDir, err)
+				}
+			}()
+		}
 
-		for gStore.FileNotPresent() {
-			time.Sleep(10 * time.Second)
+		err = scheduler.Run(ctx)
+		if errors.Is(err, context.Canceled) {
+			err = nil
 		}
 	}
 
-	gdriveHttpClient := gdriveAuthProvider.Client(ctx, token)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
 
-	driveService, err := drive.NewService(ctx, option.WithHTTPClient(gdriveHttpClient))
-	if err != nil {
-		log.Fatalf("error to creating gdrive service: %v", err)
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("error shutting down api server %v\n", err)
 	}
 
-	driveProvider := &gdrive.Provider{
-		DataPath:     "strong.csv",
-		DriveService: driveService,
+	if err != nil {
+		log.Fatalf("error syncing workouts %v", err)
 	}
+}
 
-	driveBytes, err := driveProvider.Import(ctx)
-	if err != nil {
-		log.Fatalf("error to importing gdrive file: %v", err)
+// parseSources parses the comma separated -source flag.
+func parseSources(value string) (map[string]bool, error) {
+	sources := make(map[string]bool)
+
+	for _, source := range strings.Split(value, ",") {
+		source = strings.TrimSpace(source)
+
+		switch source {
+		case sourceGDrive, sourceS3, sourceDir, sourceHTTP:
+			sources[source] = true
+		default:
+			return nil, fmt.Errorf("error unknown source %q", source)
+		}
 	}
 
-	var workouts []strong.Workout
+	return sources, nil
+}
 
-	if driveBytes != nil {
-		file := bytes.NewReader(driveBytes)
+func parseWeightUnit(unit string) (strong.WeightUnit, error) {
+	switch unit {
+	case "lb", "lbs":
+		return strong.Pounds, nil
+	case "kg", "kgs":
+		return strong.Kilograms, nil
+	default:
+		return 0, fmt.Errorf("error unknown weight unit %q", unit)
+	}
+}
 
-		workouts, err = strong.Process(file)
+// parseSchedule returns the cron schedule if one is given and otherwise the
+// interval.
+func parseSchedule(interval time.Duration, cron string) (schedule.Schedule, error) {
+	if cron != "" {
+		cronSchedule, err := schedule.ParseCron(cron)
 		if err != nil {
-			log.Printf("error processing file %v\n", err)
-			os.Exit(1)
-		}	
-	} else {
-		log.Printf("empty drive file imported\n")
-		os.Exit(1)
+			return nil, err
+		}
+
+		return cronSchedule, nil
 	}
 
-	//========================================================================
-	// Strava Flow
+	if interval <= 0 {
+		return nil, fmt.Errorf("error sync interval %s must be positive", interval)
+	}
+
+	return schedule.Every(interval), nil
+}
+
+// parseDateRange parses the -since and -until dates. Strong dates are local
+// wall-clock times labelled UTC, so the dates are parsed as UTC too. Until is
+// inclusive and is returned as the start of the following day.
+func parseDateRange(since, until string) (time.Time, time.Time, error) {
+	var sinceTime, untilTime time.Time
 
-	token, err = stravaAuthProvider.Storage.GetToken()
-	if err != nil || !token.Valid() {
-		stravaURL := stravaAuthProvider.AuthCodeURL("strava-state")
-		log.Println(stravaURL)
+	if since != "" {
+		t, err := time.Parse(time.DateOnly, since)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("error parsing since date %q: %w", since, err)
+		}
+
+		sinceTime = t
+	}
 
-		// This should be the filestorage method
-		for stravaStore.FileNotPresent() {
-			time.Sleep(10 * time.Second)
+	if until != "" {
+		t, err := time.Parse(time.DateOnly, until)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("error parsing until date %q: %w", until, err)
 		}
+
+		untilTime = t.AddDate(0, 0, 1)
+	}
+
+	if !sinceTime.IsZero() && !untilTime.IsZero() && !sinceTime.Before(untilTime) {
+		return time.Time{}, time.Time{}, fmt.Errorf("error since date %s is after until date %s", since, until)
 	}
 
-	stravaClient := stravaAuthProvider.Client(ctx, token)
+	return sinceTime, untilTime, nil
+}
 
-	stravaProvider := strava.NewProvider(log, stravaClient)
+// athleteUnit returns the weight unit, the flag if set or else the athlete's
+// measurement preference, and the athlete's bodyweight in that unit. Pounds
+// are assumed if the athlete cannot be fetched.
+func athleteUnit(ctx context.Context, log *log.Logger, provider *strava.Provider, flagUnit string) (strava.Athlete, strong.WeightUnit, float64) {
+	unit := strong.Pounds
 
-	err = stravaProvider.UploadNewWorkouts(context.Background(), workouts)
+	athlete, err := provider.GetAthlete(ctx)
 	if err != nil {
-		log.Fatalf("error uploading strava activities %v", err)
+		log.Printf("error getting strava athlete %v\n", err)
+	} else {
+		log.Printf("connected to strava as %s (%d)", athlete.Name(), athlete.ID)
+		unit = athlete.WeightUnit()
+	}
+
+	if flagUnit != "" {
+		unit, _ = parseWeightUnit(flagUnit)
 	}
 
-	log.Print("uploaded new workouts to strava")
+	return athlete, unit, athlete.Bodyweight(unit)
 }
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/auth"
+	"github.com/adiazny/strong/internal/pkg/card"
+	"github.com/adiazny/strong/internal/pkg/data"
+	"github.com/adiazny/strong/internal/pkg/export"
+	"github.com/adiazny/strong/internal/pkg/gdrive"
+	"github.com/adiazny/strong/internal/pkg/store"
+	"github.com/adiazny/strong/internal/pkg/strava"
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+const tokenPollInterval = 10 * time.Second
+
+// runSync imports the latest Strong exports from the configured sources and
+// syncs the workouts to Strava, or only logs the plan in dry-run mode. Runs
+// stop early when no export has changed since the last completed sync.
+// Clients are created on the first run that finds their tokens and reused
+// afterwards.
+func (app *application) runSync(ctx context.Context) error {
+	workouts, commit, err := app.importSource(ctx)
+	if errors.Is(err, data.ErrNotModified) {
+		app.log.Print("strong exports unchanged since the last sync")
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// -gdrive-all imports only new exports, which are not the whole history.
+	var diff strong.WorkoutDiff
+
+	if app.lastSynced != nil && !app.config.gdriveAll {
+		diff = strong.Diff(app.lastSynced, workouts)
+		app.log.Printf("strong export changes since the last sync: %s", diff)
+	}
+
+	stravaProvider, err := app.syncStravaProvider(ctx)
+	if err != nil {
+		return err
+	}
+
+	plan, err := stravaProvider.Plan(ctx, workouts)
+	if err != nil {
+		return fmt.Errorf("error planning strava sync: %w", err)
+	}
+
+	for _, planned := range plan.Activities {
+		if change, ok := diff.Change(planned.WorkoutKey); ok && planned.Action == strava.ActionUpdate {
+			app.log.Printf("updating activity %d for edited workout %s: %s", planned.ActivityID, planned.WorkoutKey, change)
+		}
+	}
+
+	if app.config.dryRun {
+		// The export is left uncommitted so the next run plans it again.
+		app.log.Printf("dry run, not applying strava sync plan: %s", plan)
+		return nil
+	}
+
+	if app.config.exportDir != "" {
+		paths, err := export.WriteFiles(app.config.exportDir, app.config.exportFormat, workouts, app.exportOptions)
+		if err != nil {
+			return fmt.Errorf("error exporting workouts: %w", err)
+		}
+
+		app.log.Printf("exported %d workouts to %s", len(paths), app.config.exportDir)
+	}
+
+	if plan.Empty() {
+		app.log.Print("no workout changes to sync to strava")
+	} else {
+		if err := stravaProvider.Apply(ctx, plan); err != nil {
+			return fmt.Errorf("error syncing strava activities: %w", err)
+		}
+
+		app.log.Printf("synced %d workout changes to strava", len(plan.Activities))
+	}
+
+	// Deferred workouts are still waiting for upload, so the export must be
+	// read again on the next run.
+	if plan.Deferred > 0 {
+		return nil
+	}
+
+	if err := commit(ctx); err != nil {
+		return err
+	}
+
+	app.lastSynced = workouts
+
+	return nil
+}
+
+func (app *application) gdriveProvider(ctx context.Context) (*gdrive.Provider, error) {
+	if provider := app.driveProvider.Load(); provider != nil {
+		return provider, nil
+	}
+
+	client, err := app.authorizedClient(ctx, app.gdriveAuthProvider, app.gdriveStore, "gdrive")
+	if err != nil {
+		return nil, err
+	}
+
+	driveService, err := drive.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("error creating gdrive service: %w", err)
+	}
+
+	provider := &gdrive.Provider{
+		DataPath:        "strong.csv",
+		DriveService:    driveService,
+		FolderID:        app.config.gdriveFolderID,
+		FolderPath:      app.config.gdriveFolder,
+		Pattern:         app.config.gdrivePattern,
+		ArchiveFolderID: app.config.gdriveArchiveID,
+		State:           app.driveState,
+	}
+
+	app.driveProvider.Store(provider)
+
+	return provider, nil
+}
+
+// syncStravaProvider returns the Strava provider, creating it once the Strava
+// token is available. It is dropped again when the athlete deauthorizes.
+func (app *application) syncStravaProvider(ctx context.Context) (*strava.Provider, error) {
+	if provider := app.stravaProvider.Load(); provider != nil {
+		return provider, nil
+	}
+
+	client, err := app.authorizedClient(ctx, app.stravaAuthProvider, app.stravaStore, "strava")
+	if err != nil {
+		return nil, err
+	}
+
+	// The athlete decides the default weight unit and the bodyweight counted
+	// towards bodyweight exercises.
+	athlete, unit, bodyweight := athleteUnit(ctx, app.log, strava.NewProvider(app.log, client), app.config.weightUnit)
+
+	app.stravaAthleteID.Store(athlete.ID)
+
+	app.exportOptions = export.Options{Unit: unit, Location: time.Local}
+	exportOptions := app.exportOptions
+
+	stravaOptions := []strava.Option{
+		strava.WithActivityCache(app.activityCache),
+		strava.WithMatcher(app.config.matcher),
+		strava.WithFormatter(app.formatter.WithUnit(unit, bodyweight)),
+		strava.WithActivityRules(app.rules),
+		strava.WithLedger(app.ledger),
+		strava.WithPrune(app.config.prune),
+		strava.WithDateRange(app.since, app.until),
+		strava.WithMaxUploads(app.config.maxUploads),
+		strava.WithPacing(app.config.pacing),
+		strava.WithBackfill(app.config.backfill),
+	}
+
+	if app.config.uploadFormat != "manual" {
+		dataType := app.config.uploadFormat
+
+		stravaOptions = append(stravaOptions, strava.WithFileUploads(dataType, func(workout strong.Workout) ([]byte, error) {
+			return export.Encode(dataType, workout, exportOptions)
+		}))
+	}
+
+	if app.config.activityPhotos {
+		cardOptions := card.Options{Unit: unit, Bodyweight: bodyweight}
+
+		stravaOptions = append(stravaOptions, strava.WithPhotos(func(workout strong.Workout, history []strong.Workout) ([]byte, error) {
+			return card.PNG(workout, history, cardOptions)
+		}))
+	}
+
+	provider := strava.NewProvider(app.log, client, stravaOptions...)
+	app.stravaProvider.Store(provider)
+
+	return provider, nil
+}
+
+// authorizedClient returns an HTTP client for the service. Without a stored
+// token it logs the authorization URL and waits for the redirect handler to
+// store one, logging a new URL whenever the previous one's state expires or
+// is used without a token being stored.
+func (app *application) authorizedClient(ctx context.Context, provider *auth.Provider, tokenStore *store.File, service string) (*http.Client, error) {
+	token, err := provider.Storage.GetToken()
+	if err == nil && token.Valid() {
+		return provider.Client(context.Background(), token), nil
+	}
+
+	ticker := time.NewTicker(tokenPollInterval)
+	defer ticker.Stop()
+
+	for tokenStore.FileNotPresent() {
+		if !provider.Pending() {
+			url, err := provider.AuthCodeURL()
+			if err != nil {
+				return nil, fmt.Errorf("error starting %s authorization: %w", service, err)
+			}
+
+			app.log.Println(url)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("error waiting for %s authorization: %w", service, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	return provider.Client(context.Background(), nil), nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/adiazny/strong/internal/pkg/strava"
+)
+
+const (
+	hubMode        = "hub.mode"
+	hubVerifyToken = "hub.verify_token"
+	hubChallenge   = "hub.challenge"
+	hubSubscribe   = "subscribe"
+
+	maxWebhookBodyBytes = 1 << 16
+)
+
+// stravaWebhookVerifyHandler answers the subscription validation request
+// Strava sends when a push subscription is created.
+func (app *application) stravaWebhookVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if query.Get(hubMode) != hubSubscribe || app.config.stravaVerifyToken == "" || query.Get(hubVerifyToken) != app.config.stravaVerifyToken {
+		http.Error(w, "invalid subscription verification request", http.StatusForbidden)
+		return
+	}
+
+	data, err := json.Marshal(map[string]string{hubChallenge: query.Get(hubChallenge)})
+	if err != nil {
+		http.Error(w, "error marshling challenge to json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// stravaWebhookEventHandler receives activity and athlete events and feeds
+// them into the sync state.
+func (app *application) stravaWebhookEventHandler(w http.ResponseWriter, r *http.Request) {
+	var event strava.WebhookEvent
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes)).Decode(&event); err != nil {
+		http.Error(w, "error decoding webhook event", http.StatusBadRequest)
+		return
+	}
+
+	// Without a configured subscription no event can be authenticated.
+	if app.config.stravaSubscriptionID == 0 || event.SubscriptionID != app.config.stravaSubscriptionID {
+		app.log.Printf("rejecting strava webhook event for subscription %d", event.SubscriptionID)
+		http.Error(w, "unknown subscription", http.StatusForbidden)
+		return
+	}
+
+	app.log.Printf("strava webhook event: %s %s %d", event.ObjectType, event.AspectType, event.ObjectID)
+
+	if event.Deauthorized() {
+		if athleteID := app.stravaAthleteID.Load(); athleteID == 0 || event.OwnerID != athleteID {
+			app.log.Printf("ignoring strava deauthorization for athlete %d, connected athlete is %d", event.OwnerID, athleteID)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if err := app.stravaStore.Remove(); err != nil {
+			app.log.Printf("error removing strava token %v", err)
+		}
+
+		app.stravaProvider.Store(nil)
+		app.stravaAthleteID.Store(0)
+
+		if err := app.activityCache.Clear(); err != nil {
+			app.log.Printf("error clearing strava activity cache %v", err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if provider := app.stravaProvider.Load(); provider != nil {
+		if err := provider.HandleWebhookEvent(r.Context(), event); err != nil {
+			app.log.Printf("error handling strava webhook event %v", err)
+		}
+	}
+
+	// Strava retries events that are not acknowledged with a 200.
+	w.WriteHeader(http.StatusOK)
+}
@@ -5,6 +5,7 @@ go 1.21
 require (
 	github.com/julienschmidt/httprouter v1.3.0
 	github.com/stretchr/testify v1.8.1
+	golang.org/x/image v0.13.0
 	golang.org/x/oauth2 v0.13.0
 	google.golang.org/api v0.148.0
 )
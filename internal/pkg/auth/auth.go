@@ -26,9 +26,12 @@ type serviceID int
 type Provider struct {
 	Config  *oauth2.Config
 	Storage Storage
+	States  *StateStore
+
+	service serviceID
 }
 
-func NewProvider(service serviceID, tokenPath, client, secrect, redirect string, store Storage) (*Provider, error) {
+func NewProvider(service serviceID, tokenPath, client, secrect, redirect string, store Storage, states *StateStore) (*Provider, error) {
 	if client == "" {
 		return nil, errors.New("client id is required")
 	}
@@ -41,7 +44,11 @@ func NewProvider(service serviceID, tokenPath, client, secrect, redirect string,
 		return nil, errors.New("redirect url is required")
 	}
 
-	var p Provider
+	if states == nil {
+		return nil, errors.New("state store is required")
+	}
+
+	p := Provider{States: states, service: service}
 
 	switch service {
 	case GDriveService:
@@ -75,8 +82,21 @@ func NewProvider(service serviceID, tokenPath, client, secrect, redirect string,
 	return &p, nil
 }
 
-func (p *Provider) AuthCodeURL(state string) string {
-	return p.Config.AuthCodeURL(state)
+// AuthCodeURL starts an authorization attempt, returning the URL to visit
+// with a newly issued state.
+func (p *Provider) AuthCodeURL() (string, error) {
+	state, err := p.States.New(p.service)
+	if err != nil {
+		return "", err
+	}
+
+	return p.Config.AuthCodeURL(state), nil
+}
+
+// Pending reports whether an authorization attempt started with AuthCodeURL
+// can still be completed.
+func (p *Provider) Pending() bool {
+	return p.States.Pending(p.service)
 }
 
 //======================
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultStateTTL is how long an authorization attempt may take.
+	DefaultStateTTL = 10 * time.Minute
+
+	stateBytes = 32
+)
+
+// ErrInvalidState is returned for OAuth state values that were never issued,
+// have expired or were already used.
+var ErrInvalidState = errors.New("invalid oauth state")
+
+type issuedState struct {
+	service serviceID
+	expires time.Time
+}
+
+// StateStore issues the OAuth state of every authorization attempt and
+// checks it on the redirect, so that only callbacks of attempts started
+// here are accepted. States are random, bound to one service, expire and
+// can be used once.
+type StateStore struct {
+	ttl    time.Duration
+	mu     sync.Mutex
+	states map[string]issuedState
+}
+
+func NewStateStore(ttl time.Duration) *StateStore {
+	if ttl <= 0 {
+		ttl = DefaultStateTTL
+	}
+
+	return &StateStore{ttl: ttl, states: make(map[string]issuedState)}
+}
+
+// New issues a state for an authorization attempt with service.
+func (s *StateStore) New(service serviceID) (string, error) {
+	random := make([]byte, stateBytes)
+
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("error generating oauth state: %w", err)
+	}
+
+	state := base64.RawURLEncoding.EncodeToString(random)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop abandoned attempts so the store does not grow.
+	for issued, entry := range s.states {
+		if now.After(entry.expires) {
+			delete(s.states, issued)
+		}
+	}
+
+	s.states[state] = issuedState{service: service, expires: now.Add(s.ttl)}
+
+	return state, nil
+}
+
+// Pending reports whether an issued state for service is neither expired nor
+// used yet.
+func (s *StateStore) Pending(service serviceID) bool {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.states {
+		if entry.service == service && !now.After(entry.expires) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Consume checks state and returns the service it was issued for. A state
+// can only be consumed once.
+func (s *StateStore) Consume(state string) (serviceID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.states[state]
+	if !ok {
+		return 0, ErrInvalidState
+	}
+
+	delete(s.states, state)
+
+	if time.Now().After(entry.expires) {
+		return 0, fmt.Errorf("%w: expired", ErrInvalidState)
+	}
+
+	return entry.service, nil
+}
@@ -0,0 +1,93 @@
+package auth_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateStore(t *testing.T) {
+	t.Parallel()
+
+	states := auth.NewStateStore(auth.DefaultStateTTL)
+
+	gdrive, err := states.New(auth.GDriveService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strava, err := states.New(auth.StravaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEqual(t, gdrive, strava)
+	assert.Len(t, gdrive, 43, "32 random bytes, base64 encoded")
+	assert.True(t, states.Pending(auth.StravaService))
+
+	service, err := states.Consume(strava)
+	assert.NoError(t, err)
+	assert.Equal(t, auth.StravaService, service)
+	assert.False(t, states.Pending(auth.StravaService), "used state should not be pending")
+	assert.True(t, states.Pending(auth.GDriveService))
+
+	_, err = states.Consume(strava)
+	assert.True(t, errors.Is(err, auth.ErrInvalidState), "replayed state should be rejected, got %v", err)
+
+	_, err = states.Consume("gdrive-state")
+	assert.True(t, errors.Is(err, auth.ErrInvalidState), "unknown state should be rejected, got %v", err)
+
+	service, err = states.Consume(gdrive)
+	assert.NoError(t, err)
+	assert.Equal(t, auth.GDriveService, service)
+}
+
+func TestStateStore_Expired(t *testing.T) {
+	t.Parallel()
+
+	states := auth.NewStateStore(time.Millisecond)
+
+	state, err := states.New(auth.StravaService)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	assert.False(t, states.Pending(auth.StravaService), "expired state should not be pending")
+
+	_, err = states.Consume(state)
+	assert.True(t, errors.Is(err, auth.ErrInvalidState), "got %v", err)
+}
+
+func TestProvider_AuthCodeURL(t *testing.T) {
+	t.Parallel()
+
+	states := auth.NewStateStore(auth.DefaultStateTTL)
+
+	provider, err := auth.NewProvider(auth.StravaService, "", "client", "secret", "http://localhost:4001/v1/redirect", nil, states)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := provider.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service, err := states.Consume(u.Query().Get("state"))
+	assert.NoError(t, err)
+	assert.Equal(t, auth.StravaService, service)
+
+	_, err = auth.NewProvider(auth.StravaService, "", "client", "secret", "http://localhost:4001/v1/redirect", nil, nil)
+	assert.Error(t, err, "a state store is required")
+}
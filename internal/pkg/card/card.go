@@ -0,0 +1,208 @@
+// Package card draws PNG summary cards of Strong workouts: an exercise table
+// with sets, best weight and volume, workout totals and PR badges. Cards are
+// drawn with the fixed 7x13 basicfont so output is identical on every
+// machine.
+package card
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/format"
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// Layout is in unscaled pixels; the finished card is scaled up by scale.
+	width      = 360
+	padding    = 12
+	lineHeight = 16
+	scale      = 2
+
+	nameChars  = 25
+	setsRight  = 228
+	bestRight  = 290
+	totalRight = width - padding
+
+	strongLayout = "2006-01-02T15:04:05Z"
+)
+
+var (
+	face = basicfont.Face7x13
+
+	background = color.RGBA{R: 0x1E, G: 0x1E, B: 0x24, A: 0xFF}
+	foreground = color.RGBA{R: 0xF2, G: 0xF2, B: 0xF2, A: 0xFF}
+	muted      = color.RGBA{R: 0x9A, G: 0x9A, B: 0xA6, A: 0xFF}
+	accent     = color.RGBA{R: 0xFC, G: 0x4C, B: 0x02, A: 0xFF}
+)
+
+// Options control how a card is drawn.
+type Options struct {
+	// Unit is the unit the workout's weights were recorded in.
+	Unit strong.WeightUnit
+	// Bodyweight, in Unit, is counted towards bodyweight exercise volume.
+	Bodyweight float64
+}
+
+// Render draws the summary card for workout. The history is used to find PRs
+// and may include the workout itself.
+func Render(workout strong.Workout, history []strong.Workout, opts Options) *image.RGBA {
+	data := format.NewData(workout, history, opts.Bodyweight)
+	unit := opts.Unit.Symbol()
+
+	rows := 4 + len(data.Exercises) + len(data.PRs)
+	if len(data.PRs) > 0 {
+		rows++
+	}
+
+	c := newCanvas(rows*lineHeight + 2*padding)
+	y := padding
+
+	c.text(padding, y, data.Workout.Name, foreground)
+	y += lineHeight
+
+	c.text(padding, y, subtitle(workout), muted)
+	y += lineHeight
+
+	c.fill(image.Rect(padding, y, width-padding, y+1), accent)
+	y += lineHeight / 2
+
+	c.text(padding, y, "EXERCISE", muted)
+	c.textRight(setsRight, y, "SETS", muted)
+	c.textRight(bestRight, y, "BEST", muted)
+	c.textRight(totalRight, y, "VOLUME", muted)
+	y += lineHeight
+
+	for _, exercise := range data.Exercises {
+		c.text(padding, y, truncate(exercise.Name, nameChars), foreground)
+		c.textRight(setsRight, y, fmt.Sprint(len(exercise.Sets)), foreground)
+		c.textRight(bestRight, y, weight(exercise.MaxWeight), foreground)
+		c.textRight(totalRight, y, fmt.Sprintf("%.0f", exercise.Volume), foreground)
+		y += lineHeight
+	}
+
+	c.fill(image.Rect(padding, y+lineHeight/4, width-padding, y+lineHeight/4+1), muted)
+	y += lineHeight / 2
+
+	c.text(padding, y, fmt.Sprintf("%d reps", data.Stats.Reps), foreground)
+	c.textRight(setsRight, y, fmt.Sprint(data.Stats.Sets), foreground)
+	c.textRight(totalRight, y, fmt.Sprintf("%.0f%s", data.Stats.Volume, unit), accent)
+	y += lineHeight
+
+	if len(data.PRs) > 0 {
+		y += lineHeight / 2
+	}
+
+	for _, pr := range data.PRs {
+		c.badge(padding, y, "PR")
+		c.text(padding+28, y, truncate(fmt.Sprintf("%s %s %s%s", pr.Exercise, pr.Kind, weight(pr.Value), unit), 44), foreground)
+		y += lineHeight
+	}
+
+	return c.img
+}
+
+// PNG draws the summary card for workout and encodes it as a PNG.
+func PNG(workout strong.Workout, history []strong.Workout, opts Options) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := png.Encode(&buf, Render(workout, history, opts)); err != nil {
+		return nil, fmt.Errorf("error encoding card png: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// canvas draws at layout size and writes each pixel scale times over, which
+// keeps the bitmap font crisp.
+type canvas struct {
+	img *image.RGBA
+}
+
+func newCanvas(height int) *canvas {
+	c := &canvas{img: image.NewRGBA(image.Rect(0, 0, width*scale, height*scale))}
+	c.fill(image.Rect(0, 0, width, height), background)
+
+	return c
+}
+
+func (c *canvas) fill(rect image.Rectangle, col color.Color) {
+	scaled := image.Rect(rect.Min.X*scale, rect.Min.Y*scale, rect.Max.X*scale, rect.Max.Y*scale)
+	draw.Draw(c.img, scaled, image.NewUniform(col), image.Point{}, draw.Src)
+}
+
+// text draws s with its top left corner at x, y.
+func (c *canvas) text(x, y int, s string, col color.Color) {
+	bounds := font.MeasureString(face, s).Ceil()
+	glyphs := image.NewRGBA(image.Rect(0, 0, bounds, face.Height))
+
+	drawer := font.Drawer{
+		Dst:  glyphs,
+		Src:  image.NewUniform(col),
+		Face: face,
+		Dot:  fixed.P(0, face.Ascent),
+	}
+	drawer.DrawString(s)
+
+	for gy := 0; gy < face.Height; gy++ {
+		for gx := 0; gx < bounds; gx++ {
+			pixel := glyphs.RGBAAt(gx, gy)
+			if pixel.A == 0 {
+				continue
+			}
+
+			c.fill(image.Rect(x+gx, y+gy, x+gx+1, y+gy+1), pixel)
+		}
+	}
+}
+
+// textRight draws s ending at x.
+func (c *canvas) textRight(x, y int, s string, col color.Color) {
+	c.text(x-font.MeasureString(face, s).Ceil(), y, s, col)
+}
+
+func (c *canvas) badge(x, y int, label string) {
+	c.fill(image.Rect(x, y, x+22, y+face.Height), accent)
+	c.text(x+4, y, label, foreground)
+}
+
+func subtitle(workout strong.Workout) string {
+	parts := make([]string, 0, 2)
+
+	if start, err := time.Parse(strongLayout, workout.Date); err == nil {
+		parts = append(parts, start.Format("Mon 2 Jan 2006 15:04"))
+	} else {
+		parts = append(parts, workout.Date)
+	}
+
+	if workout.Duration > 0 {
+		parts = append(parts, fmt.Sprintf("%d min", int(workout.Duration.Round(time.Minute).Minutes())))
+	}
+
+	return strings.Join(parts, " - ")
+}
+
+func weight(w float64) string {
+	if w == 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.1f", w)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	return s[:n-1] + "~"
+}
@@ -0,0 +1,131 @@
+package card_test
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/card"
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+var update = flag.Bool("update", false, "update golden card images in testdata")
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	previous := strong.Workout{
+		Name:     "Lower A",
+		Date:     "2022-11-07T07:15:24Z",
+		Duration: 45 * time.Minute,
+		Exercises: []strong.Exercise{
+			{Name: "Squat (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 225, Reps: 5}}},
+		},
+	}
+
+	workout := strong.Workout{
+		Name:     "Lower A",
+		Date:     "2022-11-14T07:15:24Z",
+		Duration: 50 * time.Minute,
+		Exercises: []strong.Exercise{
+			{Name: "Squat (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 225, Reps: 5}, {ID: 2, Weight: 235, Reps: 5}}},
+			{Name: "Romanian Deadlift (Barbell) Paused", Sets: []strong.Set{{ID: 1, Weight: 185, Reps: 8}}},
+			{Name: "Pull Up", Sets: []strong.Set{{ID: 1, Reps: 10}}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		golden  string
+		workout strong.Workout
+		history []strong.Workout
+		opts    card.Options
+	}{
+		{
+			name:    "PRs",
+			golden:  "lower_a.png",
+			workout: workout,
+			history: []strong.Workout{previous, workout},
+			opts:    card.Options{Unit: strong.Pounds, Bodyweight: 180},
+		},
+		{
+			name:    "no history in kilograms",
+			golden:  "lower_a_kg.png",
+			workout: workout,
+			opts:    card.Options{Unit: strong.Kilograms},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := card.Render(tt.workout, tt.history, tt.opts)
+			path := filepath.Join("testdata", tt.golden)
+
+			if *update {
+				data, err := card.PNG(tt.workout, tt.history, tt.opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.WriteFile(path, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("error reading golden image, run with -update to create it: %v", err)
+			}
+
+			want, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !samePixels(got, want) {
+				t.Errorf("card does not match %s, run with -update and inspect the diff", path)
+			}
+		})
+	}
+}
+
+func TestPNG(t *testing.T) {
+	t.Parallel()
+
+	data, err := card.PNG(strong.Workout{Name: "Empty", Date: "2022-11-14T07:15:24Z"}, nil, card.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("PNG() is not a valid png: %v", err)
+	}
+}
+
+func samePixels(got, want image.Image) bool {
+	if got.Bounds() != want.Bounds() {
+		return false
+	}
+
+	for y := got.Bounds().Min.Y; y < got.Bounds().Max.Y; y++ {
+		for x := got.Bounds().Min.X; x < got.Bounds().Max.X; x++ {
+			r1, g1, b1, a1 := got.At(x, y).RGBA()
+			r2, g2, b2, a2 := want.At(x, y).RGBA()
+
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
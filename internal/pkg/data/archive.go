@@ -0,0 +1,260 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	archiveIndexFile      = "index.jsonl"
+	archiveExt            = ".csv"
+	archiveMinRefLength   = 6
+	archiveLatestRef      = "latest"
+	archiveFileMode       = 0600
+	archiveChecksumLength = 64
+)
+
+var (
+	// ErrArchiveNotFound is returned for a reference matching no archived
+	// export.
+	ErrArchiveNotFound = errors.New("archived export not found")
+	// ErrArchiveAmbiguous is returned for a checksum prefix matching several
+	// archived exports.
+	ErrArchiveAmbiguous = errors.New("checksum prefix matches several archived exports")
+)
+
+// ArchiveEntry records one fetch of an archived export.
+type ArchiveEntry struct {
+	Checksum     string     `json:"sha256"`
+	Name         string     `json:"name,omitempty"`
+	Location     string     `json:"location,omitempty"`
+	ModifiedTime *time.Time `json:"modified_time,omitempty"`
+	FetchedAt    time.Time  `json:"fetched_at"`
+	Size         int        `json:"size"`
+	// Lenient is set when the export was parsed skipping unreadable rows,
+	// and it is replayed the same way.
+	Lenient bool `json:"lenient,omitempty"`
+}
+
+// ExportArchive keeps every raw export imported in a directory, stored once
+// by SHA-256 as <sha256[:2]>/<sha256>.csv, with index.jsonl recording every
+// fetch, so a sync can be reproduced against the exact bytes it read.
+type ExportArchive struct {
+	dir string
+	now func() time.Time
+	mu  sync.Mutex
+}
+
+func NewExportArchive(dir string) (*ExportArchive, error) {
+	if dir == "" {
+		return nil, errors.New("archive directory is required")
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating archive directory: %w", err)
+	}
+
+	return &ExportArchive{dir: dir, now: time.Now}, nil
+}
+
+// Archive stores body unless an identical export is stored already, and
+// records the fetch in the index.
+func (a *ExportArchive) Archive(ctx context.Context, metadata Metadata, body []byte) error {
+	sum := checksum(body)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.store(sum, body); err != nil {
+		return err
+	}
+
+	entry := ArchiveEntry{
+		Checksum:  sum,
+		Name:      metadata.Name,
+		Location:  metadata.Location,
+		FetchedAt: a.now().UTC(),
+		Size:      len(body),
+		Lenient:   metadata.Lenient,
+	}
+
+	if !metadata.ModifiedTime.IsZero() {
+		modified := metadata.ModifiedTime
+		entry.ModifiedTime = &modified
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error marshling archive entry: %w", err)
+	}
+
+	index, err := os.OpenFile(filepath.Join(a.dir, archiveIndexFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, archiveFileMode)
+	if err != nil {
+		return fmt.Errorf("error opening archive index: %w", err)
+	}
+
+	defer index.Close()
+
+	if _, err := index.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing archive index: %w", err)
+	}
+
+	return index.Close()
+}
+
+// Entries returns every recorded fetch, oldest first.
+func (a *ExportArchive) Entries() ([]ArchiveEntry, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.entries()
+}
+
+// Get returns the most recent fetch of the export ref refers to and its
+// data. ref is a SHA-256 checksum, a unique prefix of at least six
+// characters of one, or "latest" for the export fetched last.
+func (a *ExportArchive) Get(ref string) (ArchiveEntry, []byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entries, err := a.entries()
+	if err != nil {
+		return ArchiveEntry{}, nil, err
+	}
+
+	entry, err := resolve(entries, strings.ToLower(ref))
+	if err != nil {
+		return ArchiveEntry{}, nil, err
+	}
+
+	body, err := os.ReadFile(a.path(entry.Checksum))
+	if err != nil {
+		return ArchiveEntry{}, nil, fmt.Errorf("error reading archived export %s: %w", entry.Checksum, err)
+	}
+
+	if checksum(body) != entry.Checksum {
+		return ArchiveEntry{}, nil, fmt.Errorf("error archived export %s is corrupt", entry.Checksum)
+	}
+
+	return entry, body, nil
+}
+
+func resolve(entries []ArchiveEntry, ref string) (ArchiveEntry, error) {
+	if ref == archiveLatestRef {
+		if len(entries) == 0 {
+			return ArchiveEntry{}, ErrArchiveNotFound
+		}
+
+		return entries[len(entries)-1], nil
+	}
+
+	if len(ref) < archiveMinRefLength || len(ref) > archiveChecksumLength {
+		return ArchiveEntry{}, fmt.Errorf("%w: %q", ErrArchiveNotFound, ref)
+	}
+
+	var found *ArchiveEntry
+
+	// The index is oldest first, so the last matching fetch wins.
+	for i := range entries {
+		if !strings.HasPrefix(entries[i].Checksum, ref) {
+			continue
+		}
+
+		if found != nil && found.Checksum != entries[i].Checksum {
+			return ArchiveEntry{}, fmt.Errorf("%w: %q", ErrArchiveAmbiguous, ref)
+		}
+
+		found = &entries[i]
+	}
+
+	if found == nil {
+		return ArchiveEntry{}, fmt.Errorf("%w: %q", ErrArchiveNotFound, ref)
+	}
+
+	return *found, nil
+}
+
+func (a *ExportArchive) entries() ([]ArchiveEntry, error) {
+	entries := make([]ArchiveEntry, 0)
+
+	index, err := os.Open(filepath.Join(a.dir, archiveIndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive index: %w", err)
+	}
+
+	defer index.Close()
+
+	scanner := bufio.NewScanner(index)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry ArchiveEntry
+
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("error reading archive index line %d: %w", line, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading archive index: %w", err)
+	}
+
+	return entries, nil
+}
+
+// store writes body to its content address through a temporary file, so an
+// interrupted write never leaves a partial export behind.
+func (a *ExportArchive) store(sum string, body []byte) error {
+	path := a.path(sum)
+
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("error creating archive directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), sum+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error archiving export %s: %w", sum, err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error archiving export %s: %w", sum, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error archiving export %s: %w", sum, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error archiving export %s: %w", sum, err)
+	}
+
+	return nil
+}
+
+func (a *ExportArchive) path(sum string) string {
+	return filepath.Join(a.dir, sum[:2], sum+archiveExt)
+}
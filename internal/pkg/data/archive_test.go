@@ -0,0 +1,137 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportArchive(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	archive, err := data.NewExportArchive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	_, _, err = archive.Get("latest")
+	assert.True(t, errors.Is(err, data.ErrArchiveNotFound), "got %v", err)
+
+	// sha256("hello") and sha256("world").
+	hello := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	world := "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7"
+	modified := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	exports := []struct {
+		metadata data.Metadata
+		body     string
+	}{
+		{metadata: data.Metadata{Name: "strong.csv", Location: "gdrive:file1"}, body: "hello"},
+		{metadata: data.Metadata{Name: "strong.csv", Location: "/exports/strong.csv", ModifiedTime: modified, Lenient: true}, body: "world"},
+		{metadata: data.Metadata{Name: "upload 1", Location: "upload:1"}, body: "hello"},
+	}
+
+	for _, export := range exports {
+		if err := archive.Archive(ctx, export.metadata, []byte(export.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := archive.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, entries, 3, "every fetch should be indexed") {
+		assert.Equal(t, hello, entries[0].Checksum)
+		assert.Equal(t, "gdrive:file1", entries[0].Location)
+		assert.Equal(t, 5, entries[0].Size)
+		assert.False(t, entries[0].FetchedAt.IsZero())
+		assert.False(t, entries[0].Lenient)
+		assert.Nil(t, entries[0].ModifiedTime, "an unknown modified time should be omitted")
+		assert.Equal(t, world, entries[1].Checksum)
+		assert.True(t, entries[1].Lenient, "parse mode should be recorded")
+
+		if assert.NotNil(t, entries[1].ModifiedTime) {
+			assert.True(t, entries[1].ModifiedTime.Equal(modified))
+		}
+		assert.Equal(t, hello, entries[2].Checksum)
+		assert.Equal(t, "upload:1", entries[2].Location)
+	}
+
+	stored, err := filepath.Glob(filepath.Join(dir, "*", "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, stored, 2, "identical exports should be stored once")
+
+	tests := []struct {
+		name         string
+		ref          string
+		wantBody     string
+		wantLocation string
+		wantErr      error
+	}{
+		{name: "full checksum", ref: world, wantBody: "world", wantLocation: "/exports/strong.csv"},
+		{name: "prefix uses latest fetch", ref: hello[:8], wantBody: "hello", wantLocation: "upload:1"},
+		{name: "upper case", ref: "486EA4", wantBody: "world", wantLocation: "/exports/strong.csv"},
+		{name: "latest", ref: "latest", wantBody: "hello", wantLocation: "upload:1"},
+		{name: "prefix too short", ref: hello[:4], wantErr: data.ErrArchiveNotFound},
+		{name: "unknown", ref: "0000000000", wantErr: data.ErrArchiveNotFound},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			entry, body, err := archive.Get(tt.ref)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.wantBody, string(body))
+			assert.Equal(t, tt.wantLocation, entry.Location)
+		})
+	}
+}
+
+func TestExportArchive_Corrupt(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	archive, err := data.NewExportArchive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := archive.Archive(context.Background(), data.Metadata{Name: "strong.csv"}, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "2c", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824.csv")
+
+	if err := os.WriteFile(path, []byte("tampered"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = archive.Get("latest")
+	assert.Error(t, err)
+}
@@ -1,9 +1,222 @@
+// Package data reads Strong exports from the places they are kept: local
+// files, watched directories, HTTP uploads, Google Drive and S3 buckets.
 package data
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/strong"
 )
 
-type Importer interface {
-	Import(ctx context.Context) ([]byte, error)
+// ErrNotModified is returned by Open when the source's export has not
+// changed since it was last committed, or when it has no export yet.
+var ErrNotModified = errors.New("strong export not modified")
+
+// Metadata describes an opened export. Location is where it was read from,
+// such as a file path, gdrive:<file id> or s3://bucket/key. ModifiedTime and
+// Checksum are empty when the source does not know them.
+type Metadata struct {
+	Name         string
+	Location     string
+	ModifiedTime time.Time
+	Checksum     string
+	// Lenient is set when the export is parsed skipping unreadable rows,
+	// so archivers can record how to parse it again.
+	Lenient bool
+}
+
+// Source opens the current Strong export of one place. Callers must close
+// the returned reader.
+type Source interface {
+	Open(ctx context.Context) (io.ReadCloser, Metadata, error)
+}
+
+// Committer is implemented by sources that remember which export was last
+// synced. Commit is called once the export returned by Open has been
+// handled, so that a failed sync opens the same export again.
+type Committer interface {
+	Commit(ctx context.Context) error
+}
+
+// Lenient is implemented by sources whose exports may contain unreadable
+// rows, such as uploads, which are skipped rather than failing the import.
+type Lenient interface {
+	Lenient() bool
+}
+
+// Archiver keeps a copy of every raw export imported.
+type Archiver interface {
+	Archive(ctx context.Context, metadata Metadata, body []byte) error
+}
+
+// Multi imports the workouts of several sources as one history. It keeps
+// the workouts each source last committed, so that an unchanged source still
+// contributes them to later imports.
+type Multi struct {
+	log       *log.Logger
+	sources   []Source
+	archivers []Archiver
+
+	// Indexed like sources. Only touched by Import and Commit, which sync
+	// runs never call concurrently.
+	committed [][]strong.Workout
+	pending   [][]strong.Workout
+}
+
+func NewMulti(log *log.Logger, sources ...Source) *Multi {
+	return &Multi{
+		log:       log,
+		sources:   sources,
+		committed: make([][]strong.Workout, len(sources)),
+		pending:   make([][]strong.Workout, len(sources)),
+	}
+}
+
+// ArchiveTo archives every export Import reads to archiver before it is
+// parsed. An export that can not be archived fails the import, so that it is
+// read again by the next one.
+func (m *Multi) ArchiveTo(archiver Archiver) {
+	m.archivers = append(m.archivers, archiver)
+}
+
+// Import opens every source and merges their workouts with strong.Merge,
+// later sources winning for the same workout. Sources reporting
+// ErrNotModified contribute the workouts they last committed, if any; if all
+// report it, ErrNotModified is returned.
+func (m *Multi) Import(ctx context.Context) ([]strong.Workout, error) {
+	exports := make([][]strong.Workout, 0, len(m.sources))
+	modified := false
+
+	for i, source := range m.sources {
+		m.pending[i] = nil
+
+		workouts, metadata, err := m.importSource(ctx, source)
+		if errors.Is(err, ErrNotModified) {
+			if m.committed[i] != nil {
+				exports = append(exports, m.committed[i])
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		m.log.Printf("imported %d workouts from %s", len(workouts), metadata.Name)
+
+		m.pending[i] = workouts
+		exports = append(exports, workouts)
+		modified = true
+	}
+
+	if !modified {
+		return nil, ErrNotModified
+	}
+
+	return strong.Merge(exports...), nil
+}
+
+// Commit commits every source that remembers its last export, and keeps the
+// workouts of every source committed for later imports.
+func (m *Multi) Commit(ctx context.Context) error {
+	var errs []error
+
+	for i, source := range m.sources {
+		if committer, ok := source.(Committer); ok {
+			if err := committer.Commit(ctx); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+
+		if m.pending[i] != nil {
+			m.committed[i] = m.pending[i]
+			m.pending[i] = nil
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Multi) importSource(ctx context.Context, source Source) ([]strong.Workout, Metadata, error) {
+	reader, metadata, err := source.Open(ctx)
+	if err != nil {
+		return nil, metadata, err
+	}
+
+	defer reader.Close()
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, metadata, fmt.Errorf("error reading %s: %w", metadata.Name, err)
+	}
+
+	if lenient, ok := source.(Lenient); ok {
+		metadata.Lenient = lenient.Lenient()
+	}
+
+	for _, archiver := range m.archivers {
+		if err := archiver.Archive(ctx, metadata, body); err != nil {
+			return nil, metadata, fmt.Errorf("error archiving %s: %w", metadata.Name, err)
+		}
+	}
+
+	if metadata.Lenient {
+		workouts, warnings, err := strong.ProcessLenient(bytes.NewReader(body))
+		if err != nil {
+			return nil, metadata, fmt.Errorf("error processing %s: %w", metadata.Name, err)
+		}
+
+		if len(warnings) > 0 {
+			m.log.Printf("skipped %d unreadable rows of %s", len(warnings), metadata.Name)
+		}
+
+		return workouts, metadata, nil
+	}
+
+	workouts, err := strong.Process(bytes.NewReader(body))
+	if err != nil {
+		return nil, metadata, fmt.Errorf("error processing %s: %w", metadata.Name, err)
+	}
+
+	return workouts, metadata, nil
+}
+
+// ReadAll opens source and reads its whole export.
+func ReadAll(ctx context.Context, source Source) ([]byte, Metadata, error) {
+	reader, metadata, err := source.Open(ctx)
+	if err != nil {
+		return nil, metadata, err
+	}
+
+	defer reader.Close()
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, metadata, fmt.Errorf("error reading %s: %w", metadata.Name, err)
+	}
+
+	return body, metadata, nil
+}
+
+// checksum is the SHA-256 hex digest sources without a checksum of their own
+// report.
+func checksum(body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// opened wraps an export read into memory.
+func opened(body []byte) io.ReadCloser {
+	return io.NopCloser(bytes.NewReader(body))
 }
@@ -0,0 +1,249 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adiazny/strong/internal/pkg/data"
+	"github.com/stretchr/testify/assert"
+)
+
+const exportHeader = "Date,Workout Name,Duration,Exercise Name,Set Order,Weight,Reps,Distance,Seconds,Notes,Workout Notes,RPE\n"
+
+type stubSource struct {
+	body      string
+	err       error
+	lenient   bool
+	committed int
+}
+
+func (s *stubSource) Open(ctx context.Context) (io.ReadCloser, data.Metadata, error) {
+	if s.err != nil {
+		return nil, data.Metadata{}, s.err
+	}
+
+	return io.NopCloser(strings.NewReader(s.body)), data.Metadata{Name: "stub"}, nil
+}
+
+func (s *stubSource) Commit(ctx context.Context) error {
+	s.committed++
+	return nil
+}
+
+func (s *stubSource) Lenient() bool {
+	return s.lenient
+}
+
+func TestMulti_Import(t *testing.T) {
+	t.Parallel()
+
+	dayA := "2022-11-14 07:15:24,Day A,30m,Squat (Barbell),1,75,5,0,0,,,\n"
+	dayAEdited := "2022-11-14 07:15:24,Day A (edited),30m,Squat (Barbell),1,80,5,0,0,,,\n"
+	dayB := "2022-11-16 06:54:38,Day B,45m,Deadlift (Barbell),1,100,5,0,0,,,\n"
+	badRow := "2022-11-16 06:54:38,Day B,45m,Deadlift (Barbell)\n"
+
+	tests := []struct {
+		name    string
+		sources []*stubSource
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "merged and de-duplicated",
+			sources: []*stubSource{
+				{body: exportHeader + dayA + dayB},
+				{body: exportHeader + dayAEdited},
+			},
+			want: []string{"Day B", "Day A (edited)"},
+		},
+		{
+			name: "unmodified source left out",
+			sources: []*stubSource{
+				{err: data.ErrNotModified},
+				{body: exportHeader + dayB},
+			},
+			want: []string{"Day B"},
+		},
+		{
+			name: "all unmodified",
+			sources: []*stubSource{
+				{err: data.ErrNotModified},
+				{err: data.ErrNotModified},
+			},
+			wantErr: data.ErrNotModified,
+		},
+		{
+			name: "strict source fails on bad rows",
+			sources: []*stubSource{
+				{body: exportHeader + dayA + badRow},
+			},
+			wantErr: errors.New("any"),
+		},
+		{
+			name: "lenient source skips bad rows",
+			sources: []*stubSource{
+				{body: exportHeader + dayA + badRow, lenient: true},
+			},
+			want: []string{"Day A"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sources := make([]data.Source, 0, len(tt.sources))
+			for _, source := range tt.sources {
+				sources = append(sources, source)
+			}
+
+			multi := data.NewMulti(log.New(io.Discard, "", 0), sources...)
+
+			workouts, err := multi.Import(context.Background())
+
+			switch {
+			case errors.Is(tt.wantErr, data.ErrNotModified):
+				assert.ErrorIs(t, err, data.ErrNotModified)
+				return
+			case tt.wantErr != nil:
+				assert.Error(t, err)
+				return
+			case err != nil:
+				t.Fatal(err)
+			}
+
+			names := make([]string, 0, len(workouts))
+			for _, workout := range workouts {
+				names = append(names, workout.Name)
+			}
+
+			assert.Equal(t, tt.want, names)
+
+			if err := multi.Commit(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, source := range tt.sources {
+				assert.Equal(t, 1, source.committed)
+			}
+		})
+	}
+}
+
+func TestMulti_ImportCommitted(t *testing.T) {
+	t.Parallel()
+
+	dayA := exportHeader + "2022-11-14 07:15:24,Day A,30m,Squat (Barbell),1,75,5,0,0,,,\n"
+	dayB := exportHeader + "2022-11-16 06:54:38,Day B,45m,Deadlift (Barbell),1,100,5,0,0,,,\n"
+	dayC := exportHeader + "2022-11-18 06:30:00,Day C,40m,Bench Press (Barbell),1,60,5,0,0,,,\n"
+
+	first := &stubSource{body: dayA}
+	second := &stubSource{body: dayB}
+	multi := data.NewMulti(log.New(io.Discard, "", 0), first, second)
+	ctx := context.Background()
+
+	names := func(t *testing.T) []string {
+		t.Helper()
+
+		workouts, err := multi.Import(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := make([]string, 0, len(workouts))
+		for _, workout := range workouts {
+			got = append(got, workout.Name)
+		}
+
+		return got
+	}
+
+	assert.Equal(t, []string{"Day B", "Day A"}, names(t))
+
+	// Without a commit an unchanged source has nothing to contribute.
+	first.err = data.ErrNotModified
+	assert.Equal(t, []string{"Day B"}, names(t))
+
+	first.err = nil
+	assert.Equal(t, []string{"Day B", "Day A"}, names(t))
+
+	if err := multi.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	first.err = data.ErrNotModified
+	second.body = dayC
+	assert.Equal(t, []string{"Day C", "Day A"}, names(t), "unchanged source should keep its committed workouts")
+
+	second.err = data.ErrNotModified
+
+	_, err := multi.Import(ctx)
+	assert.ErrorIs(t, err, data.ErrNotModified)
+}
+
+type stubArchiver struct {
+	archived []string
+	lenient  []bool
+	err      error
+}
+
+func (a *stubArchiver) Archive(ctx context.Context, metadata data.Metadata, body []byte) error {
+	a.archived = append(a.archived, string(body))
+	a.lenient = append(a.lenient, metadata.Lenient)
+	return a.err
+}
+
+func TestMulti_ArchiveTo(t *testing.T) {
+	t.Parallel()
+
+	dayA := exportHeader + "2022-11-14 07:15:24,Day A,30m,Squat (Barbell),1,75,5,0,0,,,\n"
+	dayB := exportHeader + "2022-11-16 06:54:38,Day B,45m,Deadlift (Barbell),1,100,5,0,0,,,\n"
+
+	archiver := &stubArchiver{}
+	multi := data.NewMulti(log.New(io.Discard, "", 0),
+		&stubSource{body: dayA}, &stubSource{err: data.ErrNotModified}, &stubSource{body: dayB, lenient: true})
+	multi.ArchiveTo(archiver)
+
+	if _, err := multi.Import(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{dayA, dayB}, archiver.archived, "only exports read should be archived")
+	assert.Equal(t, []bool{false, true}, archiver.lenient, "parse mode should be passed on")
+
+	failing := data.NewMulti(log.New(io.Discard, "", 0), &stubSource{body: dayA})
+	failing.ArchiveTo(&stubArchiver{err: errors.New("nas offline")})
+
+	_, err := failing.Import(context.Background())
+	assert.Error(t, err)
+}
+
+func TestFile_Open(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "strong.csv")
+
+	if err := os.WriteFile(path, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, metadata, err := data.ReadAll(context.Background(), &data.File{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "hello", string(got))
+	assert.Equal(t, "strong.csv", metadata.Name)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", metadata.Checksum)
+	assert.False(t, metadata.ModifiedTime.IsZero())
+
+	_, _, err = data.ReadAll(context.Background(), &data.File{Path: filepath.Join(t.TempDir(), "missing.csv")})
+	assert.Error(t, err)
+}
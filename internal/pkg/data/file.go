@@ -0,0 +1,41 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// File is a Strong export at a fixed path.
+type File struct {
+	Path string
+}
+
+// Open reads the file, reporting its modification time and SHA-256 checksum.
+func (f *File) Open(ctx context.Context) (io.ReadCloser, Metadata, error) {
+	metadata := Metadata{Name: filepath.Base(f.Path), Location: f.Path}
+
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, metadata, fmt.Errorf("error opening %s: %w", f.Path, err)
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, metadata, fmt.Errorf("error reading %s: %w", f.Path, err)
+	}
+
+	body, err := io.ReadAll(file)
+	if err != nil {
+		return nil, metadata, fmt.Errorf("error reading %s: %w", f.Path, err)
+	}
+
+	metadata.ModifiedTime = info.ModTime()
+	metadata.Checksum = checksum(body)
+
+	return opened(body), metadata, nil
+}
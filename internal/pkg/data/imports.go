@@ -0,0 +1,278 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+const (
+	// DefaultMaxImports is how many uploads an ImportStore keeps.
+	DefaultMaxImports = 10
+
+	importIDBytes  = 16
+	importDataExt  = ".csv"
+	importMetaExt  = ".json"
+	importFileMode = 0600
+)
+
+// ErrImportNotFound is returned for an unknown import ID.
+var ErrImportNotFound = errors.New("import not found")
+
+// Import describes a Strong export received over HTTP.
+type Import struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name,omitempty"`
+	ReceivedAt time.Time        `json:"received_at"`
+	Size       int              `json:"size"`
+	Workouts   int              `json:"workouts"`
+	Warnings   []strong.Warning `json:"warnings"`
+}
+
+// ImportStore keeps the most recent uploaded exports in a directory, each as
+// <id>.csv with its description in <id>.json.
+type ImportStore struct {
+	dir        string
+	maxImports int
+	mu         sync.RWMutex
+}
+
+type ImportOption func(s *ImportStore)
+
+// WithMaxImports sets how many uploads are kept, DefaultMaxImports if unset.
+// Saving another removes the oldest.
+func WithMaxImports(n int) ImportOption {
+	return func(s *ImportStore) {
+		s.maxImports = n
+	}
+}
+
+func NewImportStore(dir string, opts ...ImportOption) (*ImportStore, error) {
+	if dir == "" {
+		return nil, errors.New("import directory is required")
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating import directory: %w", err)
+	}
+
+	s := &ImportStore{dir: dir, maxImports: DefaultMaxImports}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.maxImports < 1 {
+		return nil, errors.New("import store must keep at least one import")
+	}
+
+	return s, nil
+}
+
+// Save stores data under a new random ID, returning imp with its ID and
+// receive time set.
+func (s *ImportStore) Save(imp Import, data []byte) (Import, error) {
+	id, err := newImportID()
+	if err != nil {
+		return Import{}, err
+	}
+
+	imp.ID = id
+	imp.ReceivedAt = time.Now().UTC()
+	imp.Size = len(data)
+
+	if imp.Warnings == nil {
+		imp.Warnings = []strong.Warning{}
+	}
+
+	meta, err := json.Marshal(imp)
+	if err != nil {
+		return Import{}, fmt.Errorf("error marshling import: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.WriteFile(filepath.Join(s.dir, id+importDataExt), data, importFileMode); err != nil {
+		return Import{}, fmt.Errorf("error writing import %s: %w", id, err)
+	}
+
+	// The description is written last so a crash never leaves one without
+	// its data.
+	if err := os.WriteFile(filepath.Join(s.dir, id+importMetaExt), meta, importFileMode); err != nil {
+		return Import{}, fmt.Errorf("error writing import %s: %w", id, err)
+	}
+
+	if err := s.prune(); err != nil {
+		return Import{}, err
+	}
+
+	return imp, nil
+}
+
+// prune removes the oldest imports beyond maxImports.
+func (s *ImportStore) prune() error {
+	imports, err := s.list()
+	if err != nil {
+		return err
+	}
+
+	for len(imports) > s.maxImports {
+		id := imports[0].ID
+		imports = imports[1:]
+
+		// The description goes first, so a failure never leaves one without
+		// its data.
+		if err := os.Remove(filepath.Join(s.dir, id+importMetaExt)); err != nil {
+			return fmt.Errorf("error removing import %s: %w", id, err)
+		}
+
+		if err := os.Remove(filepath.Join(s.dir, id+importDataExt)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error removing import %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// Get returns the import with id and its data.
+func (s *ImportStore) Get(id string) (Import, []byte, error) {
+	if !validImportID(id) {
+		return Import{}, nil, ErrImportNotFound
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	imp, err := s.load(id)
+	if err != nil {
+		return Import{}, nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, id+importDataExt))
+	if err != nil {
+		return Import{}, nil, fmt.Errorf("error reading import %s: %w", id, err)
+	}
+
+	return imp, data, nil
+}
+
+// Open returns the most recently received import.
+func (s *ImportStore) Open(ctx context.Context) (io.ReadCloser, Metadata, error) {
+	s.mu.RLock()
+	imports, err := s.list()
+	s.mu.RUnlock()
+
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	// Nothing uploaded yet is not an error for the other sources synced.
+	if len(imports) == 0 {
+		return nil, Metadata{}, fmt.Errorf("%w: no strong exports have been uploaded", ErrNotModified)
+	}
+
+	latest := imports[len(imports)-1]
+
+	_, body, err := s.Get(latest.ID)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := Metadata{
+		Name:         "upload " + latest.ID,
+		Location:     "upload:" + latest.ID,
+		ModifiedTime: latest.ReceivedAt,
+		Checksum:     checksum(body),
+	}
+
+	return opened(body), metadata, nil
+}
+
+// Lenient reports that uploads are read leniently, as they were when
+// received.
+func (s *ImportStore) Lenient() bool {
+	return true
+}
+
+// list returns the stored imports, oldest first.
+func (s *ImportStore) list() ([]Import, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading import directory: %w", err)
+	}
+
+	imports := make([]Import, 0, len(entries)/2)
+
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), importMetaExt)
+		if !ok || !validImportID(id) {
+			continue
+		}
+
+		imp, err := s.load(id)
+		if err != nil {
+			return nil, err
+		}
+
+		imports = append(imports, imp)
+	}
+
+	slices.SortStableFunc(imports, func(a, b Import) int {
+		return a.ReceivedAt.Compare(b.ReceivedAt)
+	})
+
+	return imports, nil
+}
+
+func (s *ImportStore) load(id string) (Import, error) {
+	var imp Import
+
+	meta, err := os.ReadFile(filepath.Join(s.dir, id+importMetaExt))
+	if errors.Is(err, os.ErrNotExist) {
+		return imp, ErrImportNotFound
+	}
+
+	if err != nil {
+		return imp, fmt.Errorf("error reading import %s: %w", id, err)
+	}
+
+	if err := json.Unmarshal(meta, &imp); err != nil {
+		return imp, fmt.Errorf("error unmarshling import %s: %w", id, err)
+	}
+
+	return imp, nil
+}
+
+func newImportID() (string, error) {
+	id := make([]byte, importIDBytes)
+
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("error generating import id: %w", err)
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// validImportID keeps IDs from the URL from escaping the store directory.
+func validImportID(id string) bool {
+	if len(id) != importIDBytes*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(id)
+
+	return err == nil
+}
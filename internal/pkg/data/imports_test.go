@@ -0,0 +1,93 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/adiazny/strong/internal/pkg/data"
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportStore(t *testing.T) {
+	t.Parallel()
+
+	store, err := data.NewImportStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	_, _, err = data.ReadAll(ctx, store)
+	assert.ErrorIs(t, err, data.ErrNotModified, "empty store has nothing to import")
+
+	first, err := store.Save(data.Import{Name: "strong.csv", Workouts: 1}, []byte("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := store.Save(data.Import{Workouts: 2, Warnings: []strong.Warning{{Row: 3, Message: "bad row"}}}, []byte("second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, first.ID, 32)
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.Equal(t, 5, first.Size)
+	assert.Equal(t, []strong.Warning{}, first.Warnings)
+
+	imp, got, err := store.Get(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "first", string(got))
+	assert.Equal(t, first.ID, imp.ID)
+	assert.Equal(t, "strong.csv", imp.Name)
+	assert.True(t, first.ReceivedAt.Equal(imp.ReceivedAt))
+
+	got, _, err = data.ReadAll(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "second", string(got))
+
+	for _, id := range []string{"0123456789abcdef0123456789abcdef", "../../etc/passwd", ""} {
+		_, _, err = store.Get(id)
+		assert.True(t, errors.Is(err, data.ErrImportNotFound), "Get(%q) = %v", id, err)
+	}
+}
+
+func TestImportStore_MaxImports(t *testing.T) {
+	t.Parallel()
+
+	store, err := data.NewImportStore(t.TempDir(), data.WithMaxImports(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]string, 0, 3)
+
+	for _, body := range []string{"first", "second", "third"} {
+		imp, err := store.Save(data.Import{}, []byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, imp.ID)
+	}
+
+	_, _, err = store.Get(ids[0])
+	assert.True(t, errors.Is(err, data.ErrImportNotFound), "oldest import should be removed, got %v", err)
+
+	for _, id := range ids[1:] {
+		_, _, err := store.Get(id)
+		assert.NoError(t, err)
+	}
+
+	_, err = data.NewImportStore(t.TempDir(), data.WithMaxImports(0))
+	assert.Error(t, err)
+}
@@ -0,0 +1,294 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWatchPattern  = "*.csv"
+	defaultPollInterval  = 5 * time.Second
+	defaultWatchDebounce = 10 * time.Second
+)
+
+// Watcher picks up Strong exports copied into a directory, for example by
+// Syncthing or AirDrop. It polls rather than relying on file system events,
+// which network and synced folders do not always deliver. A file only counts
+// once it has not been modified for the debounce period, so partially
+// written exports are ignored. The export last committed is not opened again
+// until it changes; this is kept in memory, so the newest export is imported
+// once more after a restart.
+type Watcher struct {
+	log      *log.Logger
+	dir      string
+	pattern  string
+	interval time.Duration
+	debounce time.Duration
+	now      func() time.Time
+	ticks    <-chan time.Time
+
+	mu        sync.Mutex
+	committed Metadata
+	pending   Metadata
+}
+
+// WatcherOption configures a Watcher.
+type WatcherOption func(w *Watcher)
+
+// WithPattern sets the glob export names must match, "*.csv" by default.
+func WithPattern(pattern string) WatcherOption {
+	return func(w *Watcher) {
+		w.pattern = pattern
+	}
+}
+
+// WithPollInterval sets how often the directory is scanned.
+func WithPollInterval(interval time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.interval = interval
+	}
+}
+
+// WithDebounce sets how long a file must be left unmodified before it is
+// read.
+func WithDebounce(debounce time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.debounce = debounce
+	}
+}
+
+// WithClock replaces time.Now when deciding whether a file has settled.
+func WithClock(now func() time.Time) WatcherOption {
+	return func(w *Watcher) {
+		w.now = now
+	}
+}
+
+// WithTicks replaces the poll interval ticker, scanning once for every
+// value received.
+func WithTicks(ticks <-chan time.Time) WatcherOption {
+	return func(w *Watcher) {
+		w.ticks = ticks
+	}
+}
+
+func NewWatcher(log *log.Logger, dir string, opts ...WatcherOption) (*Watcher, error) {
+	if dir == "" {
+		return nil, errors.New("watch directory is required")
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading watch directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("error watch directory %s is not a directory", dir)
+	}
+
+	w := &Watcher{
+		log:      log,
+		dir:      dir,
+		pattern:  defaultWatchPattern,
+		interval: defaultPollInterval,
+		debounce: defaultWatchDebounce,
+		now:      time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	if _, err := filepath.Match(w.pattern, ""); err != nil {
+		return nil, fmt.Errorf("error invalid watch pattern %q: %w", w.pattern, err)
+	}
+
+	return w, nil
+}
+
+// Open reads the most recently modified settled export in the directory,
+// returning ErrNotModified if it is the one last committed.
+func (w *Watcher) Open(ctx context.Context) (io.ReadCloser, Metadata, error) {
+	reader, metadata, err := w.openNewest(ctx)
+	if err != nil {
+		return nil, metadata, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if metadata.Location == w.committed.Location && metadata.Checksum == w.committed.Checksum {
+		reader.Close()
+
+		return nil, metadata, ErrNotModified
+	}
+
+	w.pending = metadata
+
+	return reader, metadata, nil
+}
+
+// Commit marks the export last opened as synced.
+func (w *Watcher) Commit(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pending.Location != "" {
+		w.committed = w.pending
+	}
+
+	return nil
+}
+
+// Newest returns a Source opening the newest settled export whether or not
+// it was committed.
+func (w *Watcher) Newest() Source {
+	return newestExport{watcher: w}
+}
+
+type newestExport struct {
+	watcher *Watcher
+}
+
+func (n newestExport) Open(ctx context.Context) (io.ReadCloser, Metadata, error) {
+	return n.watcher.openNewest(ctx)
+}
+
+func (w *Watcher) openNewest(ctx context.Context) (io.ReadCloser, Metadata, error) {
+	files, err := w.scan()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	var latest fs.FileInfo
+
+	for _, file := range files {
+		if latest == nil || file.ModTime().After(latest.ModTime()) {
+			latest = file
+		}
+	}
+
+	if latest == nil {
+		return nil, Metadata{}, fmt.Errorf("%w: no strong exports matching %s in %s", ErrNotModified, w.pattern, w.dir)
+	}
+
+	file := File{Path: filepath.Join(w.dir, latest.Name())}
+
+	return file.Open(ctx)
+}
+
+// Run scans the directory every poll interval until ctx is cancelled and
+// calls onChange when a settled export was added or modified since the
+// previous scan. Exports present at start are not reported. If onChange
+// returns false the change is reported again on the next scan.
+func (w *Watcher) Run(ctx context.Context, onChange func(ctx context.Context) bool) error {
+	seen, err := w.snapshot()
+	if err != nil {
+		return err
+	}
+
+	ticks := w.ticks
+
+	if ticks == nil {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticks:
+		}
+
+		current, err := w.snapshot()
+		if err != nil {
+			w.log.Printf("error scanning %s: %v", w.dir, err)
+			continue
+		}
+
+		changed := ""
+
+		for name, version := range current {
+			if seen[name] != version {
+				changed = name
+				break
+			}
+		}
+
+		if changed == "" {
+			continue
+		}
+
+		w.log.Printf("strong export %s changed in %s", changed, w.dir)
+
+		if onChange(ctx) {
+			seen = current
+		}
+	}
+}
+
+// snapshot maps the settled exports to their size and modification time.
+func (w *Watcher) snapshot() (map[string]string, error) {
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]string, len(files))
+	for _, file := range files {
+		versions[file.Name()] = fmt.Sprintf("%d/%d", file.Size(), file.ModTime().UnixNano())
+	}
+
+	return versions, nil
+}
+
+// scan lists the regular files matching the pattern that have not been
+// modified within the debounce period.
+func (w *Watcher) scan() ([]fs.FileInfo, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading watch directory: %w", err)
+	}
+
+	settledBefore := w.now().Add(-w.debounce)
+
+	files := make([]fs.FileInfo, 0, len(entries))
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		if ok, _ := filepath.Match(w.pattern, entry.Name()); !ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", entry.Name(), err)
+		}
+
+		if info.ModTime().After(settledBefore) {
+			continue
+		}
+
+		files = append(files, info)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,178 @@
+package data_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeExport(t *testing.T, dir, name, contents string, modified time.Time) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcher_Open(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+
+	writeExport(t, dir, "strong.csv", "older", now.Add(-2*time.Hour))
+	writeExport(t, dir, "strong (1).csv", "newest settled", now.Add(-time.Hour))
+	writeExport(t, dir, "notes.txt", "not an export", now.Add(-time.Minute))
+	writeExport(t, dir, "strong (2).csv", "still being written", now.Add(-time.Second))
+
+	watcher, err := data.NewWatcher(log.New(io.Discard, "", 0), dir, data.WithDebounce(10*time.Second), data.WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, metadata, err := data.ReadAll(context.Background(), watcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "newest settled", string(got))
+	assert.Equal(t, "strong (1).csv", metadata.Name)
+	assert.True(t, metadata.ModifiedTime.Equal(now.Add(-time.Hour)))
+
+	empty, err := data.NewWatcher(log.New(io.Discard, "", 0), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = data.ReadAll(context.Background(), empty)
+	assert.ErrorIs(t, err, data.ErrNotModified)
+
+	_, err = data.NewWatcher(log.New(io.Discard, "", 0), filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err, "missing directory should be rejected at startup")
+}
+
+func TestWatcher_Commit(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+
+	writeExport(t, dir, "strong.csv", "first", now.Add(-time.Hour))
+
+	watcher, err := data.NewWatcher(log.New(io.Discard, "", 0), dir, data.WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	got, _, err := data.ReadAll(ctx, watcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "first", string(got))
+
+	_, _, err = data.ReadAll(ctx, watcher)
+	assert.NoError(t, err, "uncommitted export should be opened again")
+
+	if err := watcher.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = data.ReadAll(ctx, watcher)
+	assert.ErrorIs(t, err, data.ErrNotModified)
+
+	got, _, err = data.ReadAll(ctx, watcher.Newest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "first", string(got), "newest export should be opened whether or not it was committed")
+
+	writeExport(t, dir, "strong.csv", "second", now.Add(-time.Hour))
+
+	got, _, err = data.ReadAll(ctx, watcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "second", string(got), "changed export should be opened")
+}
+
+func TestWatcher_Run(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	dir := t.TempDir()
+
+	writeExport(t, dir, "strong.csv", "existing", now.Add(-time.Hour))
+
+	// Ticks are unbuffered, so each send returns only once the scan before
+	// it has finished.
+	ticks := make(chan time.Time)
+
+	watcher, err := data.NewWatcher(log.New(io.Discard, "", 0), dir,
+		data.WithTicks(ticks),
+		data.WithDebounce(time.Minute),
+		data.WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan struct{}, 10)
+	accept := make(chan bool, 10)
+
+	done := make(chan error)
+
+	go func() {
+		done <- watcher.Run(ctx, func(ctx context.Context) bool {
+			changes <- struct{}{}
+			return <-accept
+		})
+	}()
+
+	// The first tick is received after the initial snapshot.
+	ticks <- now
+
+	writeExport(t, dir, "strong (1).csv", "partial", now.Add(-time.Second))
+
+	ticks <- now
+	ticks <- now
+
+	assert.Len(t, changes, 0, "unsettled export should not be reported")
+
+	writeExport(t, dir, "strong (1).csv", "complete", now.Add(-2*time.Minute))
+
+	// A rejected change is reported again.
+	accept <- false
+	accept <- true
+
+	ticks <- now
+	ticks <- now
+	ticks <- now
+	ticks <- now
+
+	assert.Len(t, changes, 2, "rejected change should be reported again, accepted change only once")
+
+	cancel()
+
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,252 @@
+// Package export renders Strong workouts as activity files: TCX, GPX and FIT.
+package export
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/fit"
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+const (
+	FormatTCX = "tcx"
+	FormatGPX = "gpx"
+	FormatFIT = "fit"
+
+	tcxNamespace = "http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2"
+	gpxNamespace = "http://www.topografix.com/GPX/1/1"
+	gpxCreator   = "strong"
+	strongLayout = "2006-01-02T15:04:05Z"
+)
+
+// ErrNoDistance is returned by GPX for workouts without any distance sets.
+var ErrNoDistance = errors.New("error workout has no sets with distance")
+
+// Options control how workouts are rendered.
+type Options struct {
+	// Unit is the unit the workout's weights were recorded in.
+	Unit strong.WeightUnit
+	// Location is the timezone the Strong workout dates were recorded in.
+	// Nil means UTC.
+	Location *time.Location
+}
+
+// Encode renders workout in format.
+func Encode(format string, workout strong.Workout, opts Options) ([]byte, error) {
+	switch format {
+	case FormatTCX:
+		return TCX(workout, opts)
+	case FormatGPX:
+		return GPX(workout, opts)
+	case FormatFIT:
+		return fit.Encode(workout, fit.Options{Unit: opts.Unit, Location: opts.Location})
+	default:
+		return nil, fmt.Errorf("error unknown export format %q", format)
+	}
+}
+
+// WriteFiles renders every workout in format into dir, one file per workout
+// named after the workout key. It returns the paths written.
+func WriteFiles(dir, format string, workouts []strong.Workout, opts Options) ([]string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating export directory %s: %w", dir, err)
+	}
+
+	paths := make([]string, 0, len(workouts))
+
+	for _, workout := range workouts {
+		data, err := Encode(format, workout, opts)
+		if errors.Is(err, ErrNoDistance) {
+			continue
+		}
+
+		if err != nil {
+			return paths, fmt.Errorf("error exporting workout %s: %w", workout.Date, err)
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("%s.%s", workout.Key(), format))
+
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return paths, fmt.Errorf("error writing export %s: %w", path, err)
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+type tcxDatabase struct {
+	XMLName    xml.Name      `xml:"TrainingCenterDatabase"`
+	Xmlns      string        `xml:"xmlns,attr"`
+	Activities []tcxActivity `xml:"Activities>Activity"`
+}
+
+type tcxActivity struct {
+	Sport string `xml:"Sport,attr"`
+	ID    string `xml:"Id"`
+	Lap   tcxLap `xml:"Lap"`
+	Notes string `xml:"Notes,omitempty"`
+}
+
+type tcxLap struct {
+	StartTime        string          `xml:"StartTime,attr"`
+	TotalTimeSeconds float64         `xml:"TotalTimeSeconds"`
+	DistanceMeters   float64         `xml:"DistanceMeters"`
+	Calories         int             `xml:"Calories"`
+	Intensity        string          `xml:"Intensity"`
+	TriggerMethod    string          `xml:"TriggerMethod"`
+	Track            []tcxTrackpoint `xml:"Track>Trackpoint"`
+}
+
+type tcxTrackpoint struct {
+	Time string `xml:"Time"`
+}
+
+// TCX renders workout as a single-lap TCX activity. The notes hold the
+// workout name and set breakdown since TCX has no strength set records.
+func TCX(workout strong.Workout, opts Options) ([]byte, error) {
+	start, err := startTime(workout, opts.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	end := start.Add(workout.Duration)
+
+	database := tcxDatabase{
+		Xmlns: tcxNamespace,
+		Activities: []tcxActivity{{
+			Sport: "Other",
+			ID:    formatTime(start),
+			Lap: tcxLap{
+				StartTime:        formatTime(start),
+				TotalTimeSeconds: workout.Duration.Seconds(),
+				DistanceMeters:   distanceMeters(workout, opts.Unit),
+				Intensity:        "Active",
+				TriggerMethod:    "Manual",
+				Track: []tcxTrackpoint{
+					{Time: formatTime(start)},
+					{Time: formatTime(end)},
+				},
+			},
+			Notes: notes(workout, opts.Unit),
+		}},
+	}
+
+	return marshal(database)
+}
+
+type gpxDocument struct {
+	XMLName  xml.Name    `xml:"gpx"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Version  string      `xml:"version,attr"`
+	Creator  string      `xml:"creator,attr"`
+	Metadata gpxMetadata `xml:"metadata"`
+	Track    gpxTrack    `xml:"trk"`
+}
+
+type gpxMetadata struct {
+	Name string `xml:"name"`
+	Desc string `xml:"desc,omitempty"`
+	Time string `xml:"time"`
+}
+
+type gpxTrack struct {
+	Name string `xml:"name"`
+	Desc string `xml:"desc,omitempty"`
+	Type string `xml:"type"`
+}
+
+// GPX renders the cardio sets of workout, those with a distance, as a minimal
+// GPX track without points. Strong does not record routes, so the distance is
+// only carried in the description.
+func GPX(workout strong.Workout, opts Options) ([]byte, error) {
+	start, err := startTime(workout, opts.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	var desc strings.Builder
+
+	for _, exercise := range workout.Exercises {
+		for _, set := range exercise.Sets {
+			if set.Distance <= 0 {
+				continue
+			}
+
+			fmt.Fprintf(&desc, "%s: %.2f %s in %s\n", exercise.Name, set.Distance, opts.Unit.DistanceSymbol(), set.Duration)
+		}
+	}
+
+	if desc.Len() == 0 {
+		return nil, ErrNoDistance
+	}
+
+	document := gpxDocument{
+		Xmlns:   gpxNamespace,
+		Version: "1.1",
+		Creator: gpxCreator,
+		Metadata: gpxMetadata{
+			Name: workout.Name,
+			Desc: fmt.Sprintf("Duration %s", workout.Duration),
+			Time: formatTime(start),
+		},
+		Track: gpxTrack{
+			Name: workout.Name,
+			Desc: desc.String(),
+			Type: "Workout",
+		},
+	}
+
+	return marshal(document)
+}
+
+func marshal(v any) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshling xml: %w", err)
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+func notes(workout strong.Workout, unit strong.WeightUnit) string {
+	return strings.TrimSpace(workout.Name + "\n" + workout.DescriptionIn(unit))
+}
+
+// distanceMeters sums set distances, which Strong records in kilometres or
+// miles depending on unit.
+func distanceMeters(workout strong.Workout, unit strong.WeightUnit) float64 {
+	var total float64
+
+	for _, exercise := range workout.Exercises {
+		for _, set := range exercise.Sets {
+			total += set.Distance
+		}
+	}
+
+	return unit.Meters(total)
+}
+
+func startTime(workout strong.Workout, location *time.Location) (time.Time, error) {
+	if location == nil {
+		location = time.UTC
+	}
+
+	start, err := time.ParseInLocation(strongLayout, workout.Date, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing workout date %s: %w", workout.Date, err)
+	}
+
+	return start, nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,168 @@
+package export_test
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/export"
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"github.com/stretchr/testify/assert"
+)
+
+var liftWorkout = strong.Workout{
+	Name:     "Day A",
+	Date:     "2022-11-14T07:15:24Z",
+	Duration: 30 * time.Minute,
+	Exercises: []strong.Exercise{
+		{Name: "Squat (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 135, Reps: 5}}},
+	},
+}
+
+var cardioWorkout = strong.Workout{
+	Name:     "Conditioning",
+	Date:     "2022-11-15T18:00:00Z",
+	Duration: 20 * time.Minute,
+	Exercises: []strong.Exercise{
+		{Name: "Running", Sets: []strong.Set{{ID: 1, Distance: 3.2, Duration: 18 * time.Minute}}},
+	},
+}
+
+func TestTCX(t *testing.T) {
+	t.Parallel()
+
+	data, err := export.TCX(liftWorkout, export.Options{})
+	if err != nil {
+		t.Fatalf("TCX() error = %v", err)
+	}
+
+	var got struct {
+		Activities []struct {
+			Sport string `xml:"Sport,attr"`
+			ID    string `xml:"Id"`
+			Lap   struct {
+				StartTime        string  `xml:"StartTime,attr"`
+				TotalTimeSeconds float64 `xml:"TotalTimeSeconds"`
+			} `xml:"Lap"`
+			Notes string `xml:"Notes"`
+		} `xml:"Activities>Activity"`
+	}
+
+	if err := xml.Unmarshal(data, &got); err != nil {
+		t.Fatalf("xml.Unmarshal() error = %v", err)
+	}
+
+	if assert.Len(t, got.Activities, 1) {
+		activity := got.Activities[0]
+		assert.Equal(t, "2022-11-14T07:15:24Z", activity.ID)
+		assert.Equal(t, "2022-11-14T07:15:24Z", activity.Lap.StartTime)
+		assert.Equal(t, 1800.0, activity.Lap.TotalTimeSeconds)
+		assert.Equal(t, "Day A\n\nSquat (Barbell)\nSet 1: 135.0# x 5", activity.Notes)
+	}
+}
+
+func TestTCX_Distance(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		unit strong.WeightUnit
+		want float64
+	}{
+		{name: "miles with pounds", unit: strong.Pounds, want: 5149.9008},
+		{name: "kilometres with kilograms", unit: strong.Kilograms, want: 3200},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := export.TCX(cardioWorkout, export.Options{Unit: tt.unit})
+			if err != nil {
+				t.Fatalf("TCX() error = %v", err)
+			}
+
+			var got struct {
+				DistanceMeters float64 `xml:"Activities>Activity>Lap>DistanceMeters"`
+			}
+
+			if err := xml.Unmarshal(data, &got); err != nil {
+				t.Fatalf("xml.Unmarshal() error = %v", err)
+			}
+
+			assert.InDelta(t, tt.want, got.DistanceMeters, 0.001)
+		})
+	}
+}
+
+func TestTCX_Unit(t *testing.T) {
+	t.Parallel()
+
+	data, err := export.TCX(liftWorkout, export.Options{Unit: strong.Kilograms})
+	if err != nil {
+		t.Fatalf("TCX() error = %v", err)
+	}
+
+	assert.Contains(t, string(data), "Set 1: 135.0kg x 5")
+}
+
+func TestTCX_Location(t *testing.T) {
+	t.Parallel()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone database unavailable")
+	}
+
+	data, err := export.TCX(liftWorkout, export.Options{Location: newYork})
+	if err != nil {
+		t.Fatalf("TCX() error = %v", err)
+	}
+
+	assert.Contains(t, string(data), "<Id>2022-11-14T12:15:24Z</Id>")
+}
+
+func TestGPX(t *testing.T) {
+	t.Parallel()
+
+	_, err := export.GPX(liftWorkout, export.Options{})
+	assert.ErrorIs(t, err, export.ErrNoDistance)
+
+	data, err := export.GPX(cardioWorkout, export.Options{})
+	if err != nil {
+		t.Fatalf("GPX() error = %v", err)
+	}
+
+	assert.Contains(t, string(data), `<gpx xmlns="http://www.topografix.com/GPX/1/1" version="1.1" creator="strong">`)
+	assert.Contains(t, string(data), "Running: 3.20 mi in 18m0s")
+
+	data, err = export.GPX(cardioWorkout, export.Options{Unit: strong.Kilograms})
+	if err != nil {
+		t.Fatalf("GPX() error = %v", err)
+	}
+
+	assert.Contains(t, string(data), "Running: 3.20 km in 18m0s")
+	assert.Contains(t, string(data), "<time>2022-11-15T18:00:00Z</time>")
+}
+
+func TestWriteFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	paths, err := export.WriteFiles(dir, export.FormatGPX, []strong.Workout{liftWorkout, cardioWorkout}, export.Options{})
+	if err != nil {
+		t.Fatalf("WriteFiles() error = %v", err)
+	}
+
+	assert.Equal(t, []string{filepath.Join(dir, "20221115T180000.gpx")}, paths, "workouts without distance are skipped")
+
+	_, err = os.Stat(paths[0])
+	assert.NoError(t, err)
+
+	_, err = export.WriteFiles(dir, "csv", []strong.Workout{liftWorkout}, export.Options{})
+	assert.Error(t, err)
+}
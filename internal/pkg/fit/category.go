@@ -0,0 +1,97 @@
+package fit
+
+import "strings"
+
+// ExerciseCategory is the FIT exercise_category enum.
+type ExerciseCategory uint16
+
+const (
+	CategoryBenchPress       ExerciseCategory = 0
+	CategoryCalfRaise        ExerciseCategory = 1
+	CategoryCarry            ExerciseCategory = 3
+	CategoryCrunch           ExerciseCategory = 6
+	CategoryCurl             ExerciseCategory = 7
+	CategoryDeadlift         ExerciseCategory = 8
+	CategoryFlye             ExerciseCategory = 9
+	CategoryHipRaise         ExerciseCategory = 10
+	CategoryHyperextension   ExerciseCategory = 13
+	CategoryLateralRaise     ExerciseCategory = 14
+	CategoryLegCurl          ExerciseCategory = 15
+	CategoryLegRaise         ExerciseCategory = 16
+	CategoryLunge            ExerciseCategory = 17
+	CategoryOlympicLift      ExerciseCategory = 18
+	CategoryPlank            ExerciseCategory = 19
+	CategoryPlyo             ExerciseCategory = 20
+	CategoryPullUp           ExerciseCategory = 21
+	CategoryPushUp           ExerciseCategory = 22
+	CategoryRow              ExerciseCategory = 23
+	CategoryShoulderPress    ExerciseCategory = 24
+	CategoryShrug            ExerciseCategory = 26
+	CategorySitUp            ExerciseCategory = 27
+	CategorySquat            ExerciseCategory = 28
+	CategoryTricepsExtension ExerciseCategory = 30
+	CategoryRun              ExerciseCategory = 32
+	CategoryUnknown          ExerciseCategory = 65534
+)
+
+// categoryKeywords is checked in order, so more specific phrases such as
+// "leg curl" and "split squat" come before "curl" and "squat".
+var categoryKeywords = []struct {
+	keyword  string
+	category ExerciseCategory
+}{
+	{"bench press", CategoryBenchPress},
+	{"calf raise", CategoryCalfRaise},
+	{"farmer", CategoryCarry},
+	{"carry", CategoryCarry},
+	{"crunch", CategoryCrunch},
+	{"leg curl", CategoryLegCurl},
+	{"curl", CategoryCurl},
+	{"deadlift", CategoryDeadlift},
+	{"fly", CategoryFlye},
+	{"flye", CategoryFlye},
+	{"hip thrust", CategoryHipRaise},
+	{"glute bridge", CategoryHipRaise},
+	{"hyperextension", CategoryHyperextension},
+	{"back extension", CategoryHyperextension},
+	{"lateral raise", CategoryLateralRaise},
+	{"leg raise", CategoryLegRaise},
+	{"split squat", CategoryLunge},
+	{"lunge", CategoryLunge},
+	{"clean", CategoryOlympicLift},
+	{"snatch", CategoryOlympicLift},
+	{"jerk", CategoryOlympicLift},
+	{"plank", CategoryPlank},
+	{"jump", CategoryPlyo},
+	{"pull up", CategoryPullUp},
+	{"chin up", CategoryPullUp},
+	{"pulldown", CategoryPullUp},
+	{"push up", CategoryPushUp},
+	{"row", CategoryRow},
+	{"overhead press", CategoryShoulderPress},
+	{"shoulder press", CategoryShoulderPress},
+	{"military press", CategoryShoulderPress},
+	{"shrug", CategoryShrug},
+	{"sit up", CategorySitUp},
+	{"squat", CategorySquat},
+	{"leg press", CategorySquat},
+	{"triceps", CategoryTricepsExtension},
+	{"skullcrusher", CategoryTricepsExtension},
+	{"dip", CategoryTricepsExtension},
+	{"running", CategoryRun},
+	{"treadmill", CategoryRun},
+}
+
+// Category maps a Strong exercise name such as "Squat (Barbell)" to the
+// closest FIT exercise category.
+func Category(exercise string) ExerciseCategory {
+	name := strings.ToLower(exercise)
+
+	for _, candidate := range categoryKeywords {
+		if strings.Contains(name, candidate.keyword) {
+			return candidate.category
+		}
+	}
+
+	return CategoryUnknown
+}
@@ -0,0 +1,332 @@
+// Package fit encodes Strong workouts as FIT activity files containing
+// strength training set messages, as understood by Strava and Garmin Connect.
+package fit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+const (
+	headerSize      = 14
+	protocolVersion = 0x20
+	profileVersion  = 2132
+
+	definitionHeader = 0x40
+
+	// FIT timestamps count seconds since 1989-12-31T00:00:00Z.
+	fitEpoch = 631065600
+)
+
+// Global message numbers.
+const (
+	mesgFileID   uint16 = 0
+	mesgSession  uint16 = 18
+	mesgLap      uint16 = 19
+	mesgEvent    uint16 = 21
+	mesgActivity uint16 = 34
+	mesgSet      uint16 = 225
+)
+
+// Base types.
+const (
+	baseEnum    byte = 0x00
+	baseUint8   byte = 0x02
+	baseUint16  byte = 0x84
+	baseUint32  byte = 0x86
+	baseUint32z byte = 0x8C
+)
+
+// Enum values from the FIT profile.
+const (
+	fileActivity            = 4
+	manufacturerDevelopment = 255
+	eventTimer              = 0
+	eventSession            = 8
+	eventLap                = 9
+	eventActivity           = 26
+	eventTypeStart          = 0
+	eventTypeStop           = 1
+	eventTypeStopAll        = 4
+	sportTraining           = 10
+	subSportStrength        = 20
+	activityManual          = 0
+	setTypeActive           = 1
+	unitKilogram            = 1
+	unitPound               = 2
+	weightScale             = 16
+	timeScale               = 1000
+)
+
+// Options control how a workout is encoded.
+type Options struct {
+	// Unit is the unit the workout's weights were recorded in.
+	Unit strong.WeightUnit
+	// Location is the timezone the Strong workout dates were recorded in.
+	// Nil means UTC.
+	Location *time.Location
+}
+
+type field struct {
+	num      uint8
+	baseType byte
+	value    uint32
+}
+
+func (f field) size() uint8 {
+	switch f.baseType {
+	case baseUint16:
+		return 2
+	case baseUint32, baseUint32z:
+		return 4
+	default:
+		return 1
+	}
+}
+
+type message struct {
+	global uint16
+	fields []field
+}
+
+// Encode converts workout into a FIT activity file with one session, one lap
+// and a set message per Strong set. Strong does not record when each set was
+// performed, so sets are spread evenly across the workout duration.
+func Encode(workout strong.Workout, opts Options) ([]byte, error) {
+	start, err := startTime(workout, opts.Location)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing workout date %s: %w", workout.Date, err)
+	}
+
+	sets := flattenSets(workout)
+	if len(sets) == 0 {
+		return nil, errors.New("error workout has no sets")
+	}
+
+	duration := workout.Duration
+	if duration <= 0 {
+		duration = time.Duration(len(sets)) * time.Minute
+	}
+
+	end := start.Add(duration)
+	spacing := duration / time.Duration(len(sets))
+
+	var enc encoder
+
+	enc.write(message{global: mesgFileID, fields: []field{
+		{num: 0, baseType: baseEnum, value: fileActivity},
+		{num: 1, baseType: baseUint16, value: manufacturerDevelopment},
+		{num: 2, baseType: baseUint16, value: 0},
+		{num: 3, baseType: baseUint32z, value: 1},
+		{num: 4, baseType: baseUint32, value: timestamp(start)},
+	}})
+
+	enc.write(eventMessage(start, eventTypeStart))
+
+	for i, set := range sets {
+		setStart := start.Add(time.Duration(i) * spacing)
+
+		setDuration := set.Duration
+		if setDuration <= 0 || setDuration > spacing {
+			setDuration = spacing
+		}
+
+		enc.write(message{global: mesgSet, fields: []field{
+			{num: 254, baseType: baseUint32, value: timestamp(setStart.Add(setDuration))},
+			{num: 0, baseType: baseUint32, value: milliseconds(setDuration)},
+			{num: 3, baseType: baseUint16, value: uint32(set.Reps)},
+			{num: 4, baseType: baseUint16, value: scaledWeight(opts.Unit.Kilograms(set.Weight))},
+			{num: 5, baseType: baseUint8, value: setTypeActive},
+			{num: 6, baseType: baseUint32, value: timestamp(setStart)},
+			{num: 7, baseType: baseUint16, value: uint32(Category(set.exercise))},
+			{num: 9, baseType: baseUint16, value: displayUnit(opts.Unit)},
+			{num: 10, baseType: baseUint16, value: uint32(i)},
+		}})
+	}
+
+	enc.write(eventMessage(end, eventTypeStopAll))
+
+	enc.write(message{global: mesgLap, fields: []field{
+		{num: 253, baseType: baseUint32, value: timestamp(end)},
+		{num: 254, baseType: baseUint16, value: 0},
+		{num: 0, baseType: baseEnum, value: eventLap},
+		{num: 1, baseType: baseEnum, value: eventTypeStop},
+		{num: 2, baseType: baseUint32, value: timestamp(start)},
+		{num: 7, baseType: baseUint32, value: milliseconds(duration)},
+		{num: 8, baseType: baseUint32, value: milliseconds(duration)},
+		{num: 25, baseType: baseEnum, value: sportTraining},
+		{num: 39, baseType: baseEnum, value: subSportStrength},
+	}})
+
+	enc.write(message{global: mesgSession, fields: []field{
+		{num: 253, baseType: baseUint32, value: timestamp(end)},
+		{num: 254, baseType: baseUint16, value: 0},
+		{num: 0, baseType: baseEnum, value: eventSession},
+		{num: 1, baseType: baseEnum, value: eventTypeStop},
+		{num: 2, baseType: baseUint32, value: timestamp(start)},
+		{num: 5, baseType: baseEnum, value: sportTraining},
+		{num: 6, baseType: baseEnum, value: subSportStrength},
+		{num: 7, baseType: baseUint32, value: milliseconds(duration)},
+		{num: 8, baseType: baseUint32, value: milliseconds(duration)},
+		{num: 25, baseType: baseUint16, value: 0},
+		{num: 26, baseType: baseUint16, value: 1},
+	}})
+
+	_, offset := end.Zone()
+
+	enc.write(message{global: mesgActivity, fields: []field{
+		{num: 253, baseType: baseUint32, value: timestamp(end)},
+		{num: 0, baseType: baseUint32, value: milliseconds(duration)},
+		{num: 1, baseType: baseUint16, value: 1},
+		{num: 2, baseType: baseEnum, value: activityManual},
+		{num: 3, baseType: baseEnum, value: eventActivity},
+		{num: 4, baseType: baseEnum, value: eventTypeStop},
+		{num: 5, baseType: baseUint32, value: timestamp(end) + uint32(offset)},
+	}})
+
+	return enc.bytes(), nil
+}
+
+func eventMessage(at time.Time, eventType uint32) message {
+	return message{global: mesgEvent, fields: []field{
+		{num: 253, baseType: baseUint32, value: timestamp(at)},
+		{num: 0, baseType: baseEnum, value: eventTimer},
+		{num: 1, baseType: baseEnum, value: eventType},
+	}}
+}
+
+type encoder struct {
+	data  bytes.Buffer
+	local map[uint16]byte
+}
+
+// write emits a definition message the first time a global message is seen
+// and then the data message. Each global message gets its own local type.
+func (e *encoder) write(m message) {
+	if e.local == nil {
+		e.local = make(map[uint16]byte)
+	}
+
+	local, ok := e.local[m.global]
+	if !ok {
+		local = byte(len(e.local))
+		e.local[m.global] = local
+
+		e.data.WriteByte(definitionHeader | local)
+		e.data.WriteByte(0) // reserved
+		e.data.WriteByte(0) // little endian
+		binary.Write(&e.data, binary.LittleEndian, m.global)
+		e.data.WriteByte(byte(len(m.fields)))
+
+		for _, f := range m.fields {
+			e.data.Write([]byte{f.num, f.size(), f.baseType})
+		}
+	}
+
+	e.data.WriteByte(local)
+
+	for _, f := range m.fields {
+		switch f.size() {
+		case 1:
+			e.data.WriteByte(byte(f.value))
+		case 2:
+			binary.Write(&e.data, binary.LittleEndian, uint16(f.value))
+		case 4:
+			binary.Write(&e.data, binary.LittleEndian, f.value)
+		}
+	}
+}
+
+// bytes returns the complete file: header, records and trailing CRC.
+func (e *encoder) bytes() []byte {
+	header := make([]byte, headerSize)
+	header[0] = headerSize
+	header[1] = protocolVersion
+	binary.LittleEndian.PutUint16(header[2:], profileVersion)
+	binary.LittleEndian.PutUint32(header[4:], uint32(e.data.Len()))
+	copy(header[8:], ".FIT")
+	binary.LittleEndian.PutUint16(header[12:], CRC(header[:12]))
+
+	file := append(header, e.data.Bytes()...)
+
+	return binary.LittleEndian.AppendUint16(file, CRC(file))
+}
+
+type set struct {
+	strong.Set
+	exercise string
+}
+
+func flattenSets(workout strong.Workout) []set {
+	sets := make([]set, 0)
+
+	for _, exercise := range workout.Exercises {
+		for _, s := range exercise.Sets {
+			sets = append(sets, set{Set: s, exercise: exercise.Name})
+		}
+	}
+
+	return sets
+}
+
+func startTime(workout strong.Workout, location *time.Location) (time.Time, error) {
+	if location == nil {
+		location = time.UTC
+	}
+
+	// Strong dates carry a Z suffix but hold local wall-clock time.
+	return time.ParseInLocation("2006-01-02T15:04:05Z", workout.Date, location)
+}
+
+func timestamp(t time.Time) uint32 {
+	return uint32(t.Unix() - fitEpoch)
+}
+
+func milliseconds(d time.Duration) uint32 {
+	return uint32(d.Seconds() * timeScale)
+}
+
+func scaledWeight(kilograms float64) uint32 {
+	scaled := math.Round(kilograms * weightScale)
+	if scaled < 0 || scaled >= math.MaxUint16 {
+		return math.MaxUint16
+	}
+
+	return uint32(scaled)
+}
+
+func displayUnit(unit strong.WeightUnit) uint32 {
+	if unit == strong.Kilograms {
+		return unitKilogram
+	}
+
+	return unitPound
+}
+
+var crcTable = [16]uint16{
+	0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+	0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+}
+
+// CRC computes the FIT CRC-16 of data.
+func CRC(data []byte) uint16 {
+	var crc uint16
+
+	for _, b := range data {
+		tmp := crcTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ crcTable[b&0xF]
+
+		tmp = crcTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ crcTable[(b>>4)&0xF]
+	}
+
+	return crc
+}
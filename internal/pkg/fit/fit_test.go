@@ -0,0 +1,148 @@
+package fit_test
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/fit"
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	t.Parallel()
+
+	workout := strong.Workout{
+		Name:     "Day A",
+		Date:     "2022-11-14T07:15:24Z",
+		Duration: 30 * time.Minute,
+		Exercises: []strong.Exercise{
+			{Name: "Squat (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 135, Reps: 5}}},
+			{Name: "Bench Press (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 100, Reps: 8}}},
+			{Name: "Romanian Deadlift (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 185, Reps: 10}}},
+		},
+	}
+
+	data, err := fit.Encode(workout, fit.Options{Unit: strong.Pounds})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	assert.Equal(t, ".FIT", string(data[8:12]))
+	assert.Equal(t, binary.LittleEndian.Uint16(data[12:14]), fit.CRC(data[:12]), "header crc")
+	assert.Equal(t, uint32(len(data)-16), binary.LittleEndian.Uint32(data[4:8]), "data size")
+	assert.Zero(t, fit.CRC(data), "file crc over data and trailing crc")
+
+	messages := decode(t, data[14:len(data)-2])
+
+	sets := messages[225]
+	if assert.Len(t, sets, 3) {
+		assert.Equal(t, uint32(5), sets[0][3], "repetitions")
+		assert.Equal(t, uint32(980), sets[0][4], "135 lb as kg scaled by 16")
+		assert.Equal(t, uint32(fit.CategorySquat), sets[0][7])
+		assert.Equal(t, uint32(fit.CategoryBenchPress), sets[1][7])
+		assert.Equal(t, uint32(fit.CategoryDeadlift), sets[2][7])
+		assert.Equal(t, uint32(600000), sets[0][0], "sets spread over the workout")
+	}
+
+	sessions := messages[18]
+	if assert.Len(t, sessions, 1) {
+		start := time.Date(2022, 11, 14, 7, 15, 24, 0, time.UTC).Unix() - 631065600
+		assert.Equal(t, uint32(start), sessions[0][2], "start time")
+		assert.Equal(t, uint32(1800000), sessions[0][7], "total elapsed time")
+		assert.Equal(t, uint32(20), sessions[0][6], "strength training sub sport")
+	}
+
+	assert.Len(t, messages[0], 1, "file id")
+	assert.Len(t, messages[19], 1, "lap")
+	assert.Len(t, messages[34], 1, "activity")
+}
+
+func TestEncode_Errors(t *testing.T) {
+	t.Parallel()
+
+	_, err := fit.Encode(strong.Workout{Date: "not a date"}, fit.Options{})
+	assert.Error(t, err)
+
+	_, err = fit.Encode(strong.Workout{Date: "2022-11-14T07:15:24Z"}, fit.Options{})
+	assert.Error(t, err, "workout without sets")
+}
+
+func TestCategory(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]fit.ExerciseCategory{
+		"Squat (Barbell)":          fit.CategorySquat,
+		"Bulgarian Split Squat":    fit.CategoryLunge,
+		"Lying Leg Curl (Machine)": fit.CategoryLegCurl,
+		"Bicep Curl (Dumbbell)":    fit.CategoryCurl,
+		"Overhead Press (Barbell)": fit.CategoryShoulderPress,
+		"Lat Pulldown (Cable)":     fit.CategoryPullUp,
+		"Bent Over Row (Barbell)":  fit.CategoryRow,
+		"Something Else Entirely":  fit.CategoryUnknown,
+	}
+
+	for name, want := range tests {
+		assert.Equal(t, want, fit.Category(name), name)
+	}
+}
+
+// decode returns data message field values keyed by global message number
+// and field number. It supports only the normal headers Encode writes.
+func decode(t *testing.T, records []byte) map[uint16][]map[uint8]uint32 {
+	t.Helper()
+
+	type definition struct {
+		global uint16
+		fields [][2]uint8
+	}
+
+	definitions := make(map[uint8]definition)
+	messages := make(map[uint16][]map[uint8]uint32)
+
+	for len(records) > 0 {
+		header := records[0]
+		records = records[1:]
+		local := header & 0x0F
+
+		if header&0x40 != 0 {
+			def := definition{global: binary.LittleEndian.Uint16(records[2:4])}
+			count := int(records[4])
+			records = records[5:]
+
+			for i := 0; i < count; i++ {
+				def.fields = append(def.fields, [2]uint8{records[0], records[1]})
+				records = records[3:]
+			}
+
+			definitions[local] = def
+
+			continue
+		}
+
+		def, ok := definitions[local]
+		if !ok {
+			t.Fatalf("data message for undefined local type %d", local)
+		}
+
+		values := make(map[uint8]uint32)
+
+		for _, f := range def.fields {
+			switch f[1] {
+			case 1:
+				values[f[0]] = uint32(records[0])
+			case 2:
+				values[f[0]] = uint32(binary.LittleEndian.Uint16(records))
+			case 4:
+				values[f[0]] = binary.LittleEndian.Uint32(records)
+			}
+
+			records = records[f[1]:]
+		}
+
+		messages[def.global] = append(messages[def.global], values)
+	}
+
+	return messages
+}
@@ -0,0 +1,339 @@
+// Package format renders Strava activity names and descriptions from Strong
+// workouts using text/template.
+//
+// Templates are executed against a Data value:
+//
+//	.Workout            the strong.Workout being rendered (.Name, .Date, .Duration, .Exercises)
+//	.Unit               weight label, "#" for pounds or "kg"
+//	.Bodyweight         athlete bodyweight in .Unit, 0 if unknown
+//	.Description        every set in export order, as strong.Workout.DescriptionIn lists them
+//	.Exercises          exercises grouped by name in workout order
+//	  .Name             exercise name
+//	  .Sets             the strong.Set values performed (.ID, .Weight, .Reps, .Distance, .Duration, .RPE, .Notes)
+//	  .Volume           sum of weight x reps, counting bodyweight for bodyweight exercises
+//	  .MaxWeight        heaviest set weight
+//	  .E1RM             best estimated one-rep max (Epley)
+//	.Stats              workout totals
+//	  .Exercises .Sets .Reps .Volume .Duration
+//	.PRs                records beaten compared to earlier workouts in the history
+//	  .Exercise .Kind ("weight" or "e1RM") .Value .Previous
+//
+// The following functions are available in addition to the text/template
+// builtins:
+//
+//	weight   formats a weight with one decimal, e.g. 135.0
+//	tonnes   formats a volume in thousands with one decimal and a "t" suffix, e.g. 12.4t
+//	e1rm     estimates a one-rep max from weight and reps
+//	hashtag  turns text into a hashtag, e.g. "Lower A" becomes #LowerA
+//	join     joins a list of strings with a separator
+//	lower    lower-cases a string
+package format
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+const (
+	// DefaultNameTemplate uses the Strong workout name as the activity name.
+	DefaultNameTemplate = `{{.Workout.Name}}`
+
+	// DefaultDescriptionTemplate lists every set in export order, exactly as
+	// strong.Workout.Description does.
+	DefaultDescriptionTemplate = `{{.Description}}`
+
+	prKindWeight = "weight"
+	prKindE1RM   = "e1RM"
+)
+
+// Data is the model templates are executed against.
+type Data struct {
+	Workout     strong.Workout
+	Unit        string
+	Bodyweight  float64
+	Description string
+	Exercises   []Exercise
+	Stats       Stats
+	PRs         []PR
+}
+
+// Exercise summarises all sets of one exercise within a workout.
+type Exercise struct {
+	Name      string
+	Sets      []strong.Set
+	Volume    float64
+	MaxWeight float64
+	E1RM      float64
+}
+
+// Stats are totals across a whole workout.
+type Stats struct {
+	Exercises int
+	Sets      int
+	Reps      int
+	Volume    float64
+	Duration  time.Duration
+}
+
+// PR is a personal record beaten in the workout.
+type PR struct {
+	Exercise string
+	Kind     string
+	Value    float64
+	Previous float64
+}
+
+// Formatter renders activity names and descriptions.
+type Formatter struct {
+	name        *template.Template
+	description *template.Template
+	unit        strong.WeightUnit
+	bodyweight  float64
+}
+
+// New parses the given name and description templates. An empty template
+// falls back to the default.
+func New(nameTemplate, descriptionTemplate string) (*Formatter, error) {
+	if nameTemplate == "" {
+		nameTemplate = DefaultNameTemplate
+	}
+
+	if descriptionTemplate == "" {
+		descriptionTemplate = DefaultDescriptionTemplate
+	}
+
+	name, err := template.New("name").Funcs(funcs).Parse(nameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing name template: %w", err)
+	}
+
+	description, err := template.New("description").Funcs(funcs).Parse(descriptionTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing description template: %w", err)
+	}
+
+	return &Formatter{name: name, description: description}, nil
+}
+
+// Default returns a Formatter producing the same output as MapStrongWorkout.
+func Default() *Formatter {
+	formatter, err := New("", "")
+	if err != nil {
+		panic(err)
+	}
+
+	return formatter
+}
+
+// Load reads name and description templates from files. An empty path uses
+// the default template.
+func Load(namePath, descriptionPath string) (*Formatter, error) {
+	nameTemplate, err := readTemplate(namePath)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptionTemplate, err := readTemplate(descriptionPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(nameTemplate, descriptionTemplate)
+}
+
+// WithUnit returns a copy of the formatter labelling weights in unit and
+// counting bodyweight, given in unit, towards bodyweight exercises.
+func (f *Formatter) WithUnit(unit strong.WeightUnit, bodyweight float64) *Formatter {
+	formatter := *f
+	formatter.unit = unit
+	formatter.bodyweight = bodyweight
+
+	return &formatter
+}
+
+// Render returns the activity name and description for workout. The history
+// is used to detect PRs and may include the workout itself.
+func (f *Formatter) Render(workout strong.Workout, history []strong.Workout) (string, string, error) {
+	data := NewData(workout, history, f.bodyweight)
+	data.Unit = f.unit.Symbol()
+	data.Description = workout.DescriptionIn(f.unit)
+
+	var name, description strings.Builder
+
+	if err := f.name.Execute(&name, data); err != nil {
+		return "", "", fmt.Errorf("error rendering name template: %w", err)
+	}
+
+	if err := f.description.Execute(&description, data); err != nil {
+		return "", "", fmt.Errorf("error rendering description template: %w", err)
+	}
+
+	return strings.TrimSpace(name.String()), description.String(), nil
+}
+
+// NewData builds the template model for workout, labelling weights in
+// pounds. Bodyweight is counted towards bodyweight exercises.
+func NewData(workout strong.Workout, history []strong.Workout, bodyweight float64) Data {
+	data := Data{
+		Workout:     workout,
+		Unit:        strong.Pounds.Symbol(),
+		Bodyweight:  bodyweight,
+		Description: workout.DescriptionIn(strong.Pounds),
+		Exercises:   groupExercises(workout, bodyweight),
+	}
+
+	data.Stats.Duration = workout.Duration
+	data.Stats.Exercises = len(data.Exercises)
+
+	for _, exercise := range data.Exercises {
+		data.Stats.Volume += exercise.Volume
+
+		for _, set := range exercise.Sets {
+			data.Stats.Sets++
+			data.Stats.Reps += set.Reps
+		}
+	}
+
+	data.PRs = findPRs(workout, data.Exercises, history, bodyweight)
+
+	return data
+}
+
+func groupExercises(workout strong.Workout, bodyweight float64) []Exercise {
+	exercises := make([]Exercise, 0)
+	index := make(map[string]int)
+
+	for _, exercise := range workout.Exercises {
+		i, ok := index[exercise.Name]
+		if !ok {
+			i = len(exercises)
+			index[exercise.Name] = i
+			exercises = append(exercises, Exercise{Name: exercise.Name})
+		}
+
+		for _, set := range exercise.Sets {
+			exercises[i].add(set, exercise.Load(set, bodyweight))
+		}
+	}
+
+	return exercises
+}
+
+func (exercise *Exercise) add(set strong.Set, load float64) {
+	exercise.Sets = append(exercise.Sets, set)
+	exercise.Volume += load * float64(set.Reps)
+
+	if set.Weight > exercise.MaxWeight {
+		exercise.MaxWeight = set.Weight
+	}
+
+	if e1rm := estimateOneRepMax(load, set.Reps); e1rm > exercise.E1RM {
+		exercise.E1RM = e1rm
+	}
+}
+
+// findPRs compares each exercise against the best results of workouts that
+// started earlier. Exercises never performed before do not count as PRs.
+func findPRs(workout strong.Workout, exercises []Exercise, history []strong.Workout, bodyweight float64) []PR {
+	best := make(map[string]*Exercise)
+
+	for _, previous := range history {
+		if previous.Date >= workout.Date {
+			continue
+		}
+
+		for _, exercise := range groupExercises(previous, bodyweight) {
+			exercise := exercise
+
+			current, ok := best[exercise.Name]
+			if !ok {
+				best[exercise.Name] = &exercise
+				continue
+			}
+
+			current.MaxWeight = max(current.MaxWeight, exercise.MaxWeight)
+			current.E1RM = max(current.E1RM, exercise.E1RM)
+		}
+	}
+
+	prs := make([]PR, 0)
+
+	for _, exercise := range exercises {
+		previous, ok := best[exercise.Name]
+		if !ok {
+			continue
+		}
+
+		if exercise.MaxWeight > previous.MaxWeight {
+			prs = append(prs, PR{Exercise: exercise.Name, Kind: prKindWeight, Value: exercise.MaxWeight, Previous: previous.MaxWeight})
+		}
+
+		if exercise.E1RM > previous.E1RM {
+			prs = append(prs, PR{Exercise: exercise.Name, Kind: prKindE1RM, Value: exercise.E1RM, Previous: previous.E1RM})
+		}
+	}
+
+	return prs
+}
+
+// estimateOneRepMax uses the Epley formula.
+func estimateOneRepMax(weight float64, reps int) float64 {
+	switch {
+	case reps <= 0:
+		return 0
+	case reps == 1:
+		return weight
+	default:
+		return weight * (1 + float64(reps)/30)
+	}
+}
+
+var funcs = template.FuncMap{
+	"weight": func(weight float64) string {
+		return fmt.Sprintf("%.1f", weight)
+	},
+	"tonnes": func(volume float64) string {
+		return fmt.Sprintf("%.1ft", volume/1000)
+	},
+	"e1rm":    estimateOneRepMax,
+	"hashtag": hashtag,
+	"join":    strings.Join,
+	"lower":   strings.ToLower,
+}
+
+func hashtag(text string) string {
+	var builder strings.Builder
+
+	builder.WriteString("#")
+
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+		}
+	}
+
+	return builder.String()
+}
+
+func readTemplate(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading template %s: %w", path, err)
+	}
+
+	if len(data) == 0 {
+		return "", fmt.Errorf("error template file %s is empty", path)
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,155 @@
+package format_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/format"
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatter_Render(t *testing.T) {
+	t.Parallel()
+
+	previous := strong.Workout{
+		Name:     "Lower A",
+		Date:     "2022-11-07T07:15:24Z",
+		Duration: 45 * time.Minute,
+		Exercises: []strong.Exercise{
+			{Name: "Squat (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 225, Reps: 5}}},
+			{Name: "Deadlift (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 315, Reps: 3}}},
+		},
+	}
+
+	workout := strong.Workout{
+		Name:     "Lower A",
+		Date:     "2022-11-14T07:15:24Z",
+		Duration: 50 * time.Minute,
+		Exercises: []strong.Exercise{
+			{Name: "Squat (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 225, Reps: 5}}},
+			{Name: "Squat (Barbell)", Sets: []strong.Set{{ID: 2, Weight: 235, Reps: 5}}},
+			{Name: "Deadlift (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 315, Reps: 3}}},
+			{Name: "Leg Press", Sets: []strong.Set{{ID: 1, Weight: 400, Reps: 10}}},
+		},
+	}
+
+	history := []strong.Workout{workout, previous}
+
+	tests := []struct {
+		name            string
+		nameTemplate    string
+		descTemplate    string
+		unit            strong.WeightUnit
+		bodyweight      float64
+		wantName        string
+		wantDescription string
+		wantErr         bool
+	}{
+		{
+			name:            "default templates match workout description",
+			wantName:        "Lower A",
+			wantDescription: workout.Description(),
+		},
+		{
+			name:            "kilograms",
+			unit:            strong.Kilograms,
+			wantName:        "Lower A",
+			wantDescription: workout.DescriptionIn(strong.Kilograms),
+		},
+		{
+			name:            "volume in title",
+			nameTemplate:    `{{.Workout.Name}} · {{tonnes .Stats.Volume}} volume`,
+			descTemplate:    `{{.Stats.Exercises}} exercises, {{.Stats.Sets}} sets, {{.Stats.Reps}} reps`,
+			wantName:        "Lower A · 7.2t volume",
+			wantDescription: "3 exercises, 4 sets, 23 reps",
+		},
+		{
+			name:            "PRs and hashtags",
+			descTemplate:    `{{range .PRs}}PR {{.Exercise}} {{.Kind}} {{weight .Value}} (was {{weight .Previous}}) {{end}}{{hashtag .Workout.Name}}`,
+			wantName:        "Lower A",
+			wantDescription: "PR Squat (Barbell) weight 235.0 (was 225.0) PR Squat (Barbell) e1RM 274.2 (was 262.5) #LowerA",
+		},
+		{
+			name:         "invalid template",
+			nameTemplate: `{{.Workout.Name`,
+			wantErr:      true,
+		},
+		{
+			name:         "unknown field",
+			descTemplate: `{{.Workout.Missing}}`,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			formatter, err := format.New(tt.nameTemplate, tt.descTemplate)
+			if err == nil {
+				var name, description string
+
+				name, description, err = formatter.WithUnit(tt.unit, tt.bodyweight).Render(workout, history)
+				if err == nil {
+					assert.Equal(t, tt.wantName, name)
+					assert.Equal(t, tt.wantDescription, description)
+				}
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatter_RenderInterleaved(t *testing.T) {
+	t.Parallel()
+
+	workout := strong.Workout{
+		Name: "Superset",
+		Date: "2023-01-02T10:00:00Z",
+		Exercises: []strong.Exercise{
+			{Name: "Bench Press (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 185, Reps: 5}}},
+			{Name: "Pull Up", Sets: []strong.Set{{ID: 1, Reps: 8}}},
+			{Name: "Bench Press (Barbell)", Sets: []strong.Set{{ID: 2, Weight: 185, Reps: 5}}},
+			{Name: "Pull Up", Sets: []strong.Set{{ID: 2, Reps: 8}}},
+		},
+	}
+
+	_, description, err := format.Default().Render(workout, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, workout.Description(), description, "default template should keep the export order of interleaved sets")
+}
+
+func TestFormatter_RenderBodyweight(t *testing.T) {
+	t.Parallel()
+
+	workout := strong.Workout{
+		Name: "Upper B",
+		Date: "2022-11-15T07:15:24Z",
+		Exercises: []strong.Exercise{
+			{Name: "Pull Up", Sets: []strong.Set{{ID: 1, Reps: 10}}},
+			{Name: "Pull Up (Assisted)", Sets: []strong.Set{{ID: 1, Weight: 20, Reps: 10}}},
+			{Name: "Bench Press (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 60, Reps: 5}}},
+		},
+	}
+
+	formatter, err := format.New("", `{{weight .Stats.Volume}}{{.Unit}} at {{weight .Bodyweight}}{{.Unit}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, description, err := formatter.WithUnit(strong.Kilograms, 80).Render(workout, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "1700.0kg at 80.0kg", description)
+}
@@ -0,0 +1,141 @@
+package gdrive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/adiazny/strong/internal/pkg/data"
+	"google.golang.org/api/drive/v3"
+)
+
+// Export is one downloaded Strong export.
+type Export struct {
+	FileID       string
+	Name         string
+	ModifiedTime string
+	Data         []byte
+}
+
+// ImportAll downloads every matching export not already processed, oldest
+// first, so they can be merged with strong.Merge. It returns
+// data.ErrNotModified when there is none. Like Open, the processed exports
+// are only recorded by Commit.
+func (p *Provider) ImportAll(ctx context.Context) ([]Export, error) {
+	var state State
+
+	if p.State != nil {
+		var err error
+
+		state, err = p.State.Load()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := p.searchAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 && len(state.Processed) == 0 {
+		return nil, fmt.Errorf("no google drive files found for %s", p.namePattern())
+	}
+
+	processed := make(map[string]string, len(state.Processed)+len(files))
+	for id, version := range state.Processed {
+		processed[id] = version
+	}
+
+	exports := make([]Export, 0)
+	downloaded := make([]*drive.File, 0)
+
+	// Listed newest first.
+	slices.Reverse(files)
+
+	for _, file := range files {
+		if state.processed(file) {
+			continue
+		}
+
+		data, err := p.download(ctx, file.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		exports = append(exports, Export{FileID: file.Id, Name: file.Name, ModifiedTime: file.ModifiedTime, Data: data})
+		downloaded = append(downloaded, file)
+		processed[file.Id] = fileVersion(file)
+	}
+
+	if len(exports) == 0 {
+		p.pending = nil
+		p.archive = nil
+
+		return nil, data.ErrNotModified
+	}
+
+	state.Processed = processed
+	p.pending = &state
+	p.archive = downloaded
+
+	return exports, nil
+}
+
+// searchAll returns every export, most recently modified first. Archived
+// exports may have left none.
+func (p *Provider) searchAll(ctx context.Context) ([]*drive.File, error) {
+	query, err := p.query(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*drive.File, 0)
+
+	call := p.DriveService.Files.List().PageSize(driveFilesPageSize).OrderBy(modifiedTimeDescending).Q(query).Fields(fileFields)
+
+	err = call.Pages(ctx, func(list *drive.FileList) error {
+		for _, file := range list.Files {
+			if file.Id != "" && p.matches(file.Name) {
+				files = append(files, file)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error searching drive files %s: %w", query, err)
+	}
+
+	return files, nil
+}
+
+// archiveFiles moves files into ArchiveFolderID, removing them from their
+// other folders. It tries every file and returns the combined errors.
+func (p *Provider) archiveFiles(ctx context.Context, files []*drive.File) error {
+	if p.ArchiveFolderID == "" {
+		return nil
+	}
+
+	var errs []error
+
+	for _, file := range files {
+		if slices.Contains(file.Parents, p.ArchiveFolderID) {
+			continue
+		}
+
+		call := p.DriveService.Files.Update(file.Id, &drive.File{}).AddParents(p.ArchiveFolderID).Fields("id")
+
+		if len(file.Parents) > 0 {
+			call = call.RemoveParents(strings.Join(file.Parents, ","))
+		}
+
+		if _, err := call.Context(ctx).Do(); err != nil {
+			errs = append(errs, fmt.Errorf("error archiving drive file %s: %w", file.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,100 @@
+package gdrive_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/adiazny/strong/internal/pkg/data"
+	"github.com/adiazny/strong/internal/pkg/gdrive"
+	"github.com/adiazny/strong/internal/pkg/gdrive/drivetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvider_ImportAll(t *testing.T) {
+	t.Parallel()
+
+	server := drivetest.NewServer()
+	defer server.Close()
+
+	exports := server.AddFolder("Strong", "")
+	archive := server.AddFolder("Archive", "")
+
+	first := server.AddFile(drivetest.File{Name: "strong.csv", Parents: []string{exports.ID}, Data: []byte("first")})
+	server.AddFile(drivetest.File{Name: "strong (1).csv", Parents: []string{exports.ID}, Data: []byte("second")})
+	server.AddFile(drivetest.File{Name: "notes.txt", Parents: []string{exports.ID}, Data: []byte("notes")})
+
+	provider := newDriveService(t, server, &gdrive.Provider{
+		DataPath:        "strong.csv",
+		Pattern:         "strong*.csv",
+		FolderID:        exports.ID,
+		ArchiveFolderID: archive.ID,
+		State:           newStateFile(t),
+	})
+	ctx := context.Background()
+
+	got, err := provider.ImportAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"first", "second"}, exportData(got), "exports should be oldest first")
+
+	if err := provider.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	archived, _ := server.File(first.ID)
+	assert.Equal(t, []string{archive.ID}, archived.Parents)
+
+	_, err = provider.ImportAll(ctx)
+	assert.True(t, errors.Is(err, data.ErrNotModified), "got %v", err)
+
+	server.AddFile(drivetest.File{Name: "strong (2).csv", Parents: []string{exports.ID}, Data: []byte("third")})
+
+	got, err = provider.ImportAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"third"}, exportData(got), "processed exports should be skipped")
+}
+
+func TestProvider_ImportAllReprocessesEdits(t *testing.T) {
+	t.Parallel()
+
+	server := drivetest.NewServer()
+	defer server.Close()
+
+	export := server.AddFile(drivetest.File{Name: "strong.csv", Data: []byte("v1")})
+	server.AddFile(drivetest.File{Name: "strong.csv", Data: []byte("other")})
+
+	provider := newDriveService(t, server, &gdrive.Provider{DataPath: "strong.csv", State: newStateFile(t)})
+	ctx := context.Background()
+
+	if _, err := provider.ImportAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := provider.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	server.UpdateFile(export.ID, []byte("v2"))
+
+	got, err := provider.ImportAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"v2"}, exportData(got))
+}
+
+func exportData(exports []gdrive.Export) []string {
+	data := make([]string, 0, len(exports))
+	for _, export := range exports {
+		data = append(data, string(export.Data))
+	}
+
+	return data
+}
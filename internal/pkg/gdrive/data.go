@@ -2,63 +2,241 @@
 package gdrive
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
-	"path"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/data"
 
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 )
 
 const (
-	createdTimeDescending = "createdTime desc"
-	driveFilesPageSize    = 5
+	modifiedTimeDescending = "modifiedTime desc"
+	driveFilesPageSize     = 100
+
+	fileFields    googleapi.Field = "nextPageToken, files(id, name, modifiedTime, md5Checksum, parents)"
+	changesFields googleapi.Field = "nextPageToken, newStartPageToken, changes(fileId, removed, file(name))"
 )
 
 type Provider struct {
 	//logger
 	DataPath     string
 	DriveService *drive.Service
+	// FolderID or FolderPath, a slash separated path from My Drive such as
+	// "Backups/Strong", limits the search to one folder. FolderID wins if
+	// both are set.
+	FolderID   string
+	FolderPath string
+	// Pattern, if set, is a path.Match glob such as "strong*.csv" matched
+	// against file names instead of the base name of DataPath.
+	Pattern string
+	// ArchiveFolderID, if set, is the folder Commit moves exports imported
+	// by ImportAll to. Moving files needs the full drive scope.
+	ArchiveFolderID string
+	// State, if set, remembers the last imported export so Open can skip
+	// unchanged files.
+	State *StateFile
+
+	pending          *State
+	archive          []*drive.File
+	resolvedFolderID string
 }
 
-func (p *Provider) Import(ctx context.Context) ([]byte, error) {
-	fileName := path.Base(p.DataPath)
+// Open downloads the newest export. With a State it first asks the Drive
+// changes API whether anything named like the export changed, then compares
+// the newest file's metadata, returning data.ErrNotModified if it was
+// already imported. The new state is only saved by Commit.
+func (p *Provider) Open(ctx context.Context) (io.ReadCloser, data.Metadata, error) {
+	var state State
+
+	if p.State != nil {
+		var err error
+
+		state, err = p.State.Load()
+		if err != nil {
+			return nil, data.Metadata{}, err
+		}
+	}
+
+	pageToken := state.PageToken
+
+	if p.State != nil {
+		changed, newPageToken, err := p.changedSince(ctx, pageToken, state.FileID)
+		if err != nil {
+			return nil, data.Metadata{}, err
+		}
 
-	driveFile, err := p.searchLatest(fileName)
+		pageToken = newPageToken
+
+		if !changed {
+			return nil, data.Metadata{}, p.notModified(state, pageToken)
+		}
+	}
+
+	driveFile, err := p.searchLatest(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error searching drive file %s: %w", fileName, err)
+		return nil, data.Metadata{}, err
 	}
 
-	if driveFile.Id == "" {
-		return nil, fmt.Errorf("error file ID is empty for %s", fileName)
+	metadata := fileMetadata(driveFile)
+
+	if p.State != nil && state.Same(driveFile.Id, driveFile.ModifiedTime, driveFile.Md5Checksum) {
+		return nil, metadata, p.notModified(state, pageToken)
 	}
 
-	fileBytes, err := p.download(driveFile.Id)
+	fileBytes, err := p.download(ctx, driveFile.Id)
+	if err != nil {
+		return nil, metadata, err
+	}
+
+	state.FileID = driveFile.Id
+	state.ModifiedTime = driveFile.ModifiedTime
+	state.MD5Checksum = driveFile.Md5Checksum
+	state.PageToken = pageToken
+	p.pending = &state
+
+	return io.NopCloser(bytes.NewReader(fileBytes)), metadata, nil
+}
+
+// Latest downloads the newest export regardless of the saved state, without
+// changing it.
+func (p *Provider) Latest(ctx context.Context) ([]byte, error) {
+	driveFile, err := p.searchLatest(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return fileBytes, nil
+	return p.download(ctx, driveFile.Id)
+}
+
+// Commit saves the state of the last Open or ImportAll once its workouts
+// have been handled, so a failed sync retries the same exports, then moves
+// exports imported by ImportAll to ArchiveFolderID if set.
+func (p *Provider) Commit(ctx context.Context) error {
+	if p.pending == nil {
+		return nil
+	}
+
+	if p.State != nil {
+		if err := p.State.Save(*p.pending); err != nil {
+			return err
+		}
+	}
+
+	p.pending = nil
+
+	archived := p.archive
+	p.archive = nil
+
+	return p.archiveFiles(ctx, archived)
+}
+
+// notModified advances the saved page token past changes that did not touch
+// the export and returns data.ErrNotModified.
+func (p *Provider) notModified(state State, pageToken string) error {
+	p.pending = nil
+	p.archive = nil
+
+	if pageToken != state.PageToken {
+		state.PageToken = pageToken
+
+		if err := p.State.Save(state); err != nil {
+			return err
+		}
+	}
+
+	return data.ErrNotModified
 }
 
-func (p *Provider) searchLatest(fileName string) (*drive.File, error) {
-	query := fmt.Sprintf("name = '%s'", fileName)
+// changedSince reports whether a file matching the export name, or the file
+// with fileID, changed after pageToken, and returns the token to list later
+// changes from. Without a token, or without a previous import, it reports a
+// change.
+func (p *Provider) changedSince(ctx context.Context, pageToken, fileID string) (bool, string, error) {
+	if pageToken == "" || fileID == "" {
+		start, err := p.DriveService.Changes.GetStartPageToken().Context(ctx).Do()
+		if err != nil {
+			return false, "", fmt.Errorf("error getting drive changes start page token: %w", err)
+		}
+
+		return true, start.StartPageToken, nil
+	}
 
-	fileListCall, err := p.DriveService.Files.List().PageSize(driveFilesPageSize).OrderBy(createdTimeDescending).Q(query).Do()
+	changed := false
+
+	for {
+		changes, err := p.DriveService.Changes.List(pageToken).Fields(changesFields).Context(ctx).Do()
+		if err != nil {
+			return false, "", fmt.Errorf("error listing drive changes: %w", err)
+		}
+
+		for _, change := range changes.Changes {
+			if change.FileId == fileID || (change.File != nil && p.matches(change.File.Name)) {
+				changed = true
+			}
+		}
+
+		if changes.NextPageToken == "" {
+			return changed, changes.NewStartPageToken, nil
+		}
+
+		pageToken = changes.NextPageToken
+	}
+}
+
+// searchLatest returns the most recently modified export. Drive can only
+// prefix match names, so glob patterns are checked here page by page.
+func (p *Provider) searchLatest(ctx context.Context) (*drive.File, error) {
+	query, err := p.query(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(fileListCall.Files) == 0 {
-		return nil, errors.New("no google drive files found")
+	call := p.DriveService.Files.List().PageSize(driveFilesPageSize).OrderBy(modifiedTimeDescending).Q(query).Fields(fileFields)
+
+	var latest *drive.File
+
+	errFound := errors.New("found")
+
+	err = call.Pages(ctx, func(files *drive.FileList) error {
+		for _, file := range files.Files {
+			if p.matches(file.Name) {
+				latest = file
+				return errFound
+			}
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errFound) {
+		return nil, fmt.Errorf("error searching drive files %s: %w", query, err)
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("no google drive files found for %s", p.namePattern())
+	}
+
+	if latest.Id == "" {
+		return nil, fmt.Errorf("error file ID is empty for %s", latest.Name)
 	}
 
-	return fileListCall.Files[0], nil
+	return latest, nil
+}
+
+// fileMetadata describes a listed Drive file.
+func fileMetadata(file *drive.File) data.Metadata {
+	modified, _ := time.Parse(time.RFC3339, file.ModifiedTime)
+
+	return data.Metadata{Name: file.Name, Location: "gdrive:" + file.Id, ModifiedTime: modified, Checksum: file.Md5Checksum}
 }
 
-func (p *Provider) download(fileId string) ([]byte, error) {
-	response, err := p.DriveService.Files.Get(fileId).Download()
+func (p *Provider) download(ctx context.Context, fileId string) ([]byte, error) {
+	response, err := p.DriveService.Files.Get(fileId).Context(ctx).Download()
 	if err != nil {
 		return nil, fmt.Errorf("error downloading file %s: %w", fileId, err)
 	}
@@ -0,0 +1,240 @@
+package gdrive_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/adiazny/strong/internal/pkg/data"
+	"github.com/adiazny/strong/internal/pkg/gdrive"
+	"github.com/adiazny/strong/internal/pkg/gdrive/drivetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDriveService(t *testing.T, server *drivetest.Server, provider *gdrive.Provider) *gdrive.Provider {
+	t.Helper()
+
+	service, err := server.Service(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider.DriveService = service
+
+	return provider
+}
+
+func newStateFile(t *testing.T) *gdrive.StateFile {
+	t.Helper()
+
+	state, err := gdrive.NewStateFile(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return state
+}
+
+func openExport(ctx context.Context, provider *gdrive.Provider) ([]byte, error) {
+	body, _, err := data.ReadAll(ctx, provider)
+
+	return body, err
+}
+
+func TestProvider_Open(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		provider gdrive.Provider
+		seed     func(server *drivetest.Server, provider *gdrive.Provider)
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "newest modified export",
+			provider: gdrive.Provider{DataPath: "strong.csv"},
+			seed: func(server *drivetest.Server, provider *gdrive.Provider) {
+				older := server.AddFile(drivetest.File{Name: "strong.csv", Data: []byte("older")})
+				server.AddFile(drivetest.File{Name: "strong.csv", Data: []byte("newer")})
+				server.UpdateFile(older.ID, []byte("re-exported"))
+			},
+			want: "re-exported",
+		},
+		{
+			name:     "skips trashed and other names",
+			provider: gdrive.Provider{DataPath: "./data/strong.csv"},
+			seed: func(server *drivetest.Server, provider *gdrive.Provider) {
+				server.AddFile(drivetest.File{Name: "strong.csv", Data: []byte("kept")})
+				trashed := server.AddFile(drivetest.File{Name: "strong.csv", Data: []byte("trashed")})
+				server.TrashFile(trashed.ID)
+				server.AddFile(drivetest.File{Name: "other.csv", Data: []byte("other")})
+			},
+			want: "kept",
+		},
+		{
+			name:     "name with quotes",
+			provider: gdrive.Provider{DataPath: `Adam's \strong.csv`},
+			seed: func(server *drivetest.Server, provider *gdrive.Provider) {
+				server.AddFile(drivetest.File{Name: `Adam's \strong.csv`, Data: []byte("quoted")})
+			},
+			want: "quoted",
+		},
+		{
+			name:     "glob pattern",
+			provider: gdrive.Provider{DataPath: "strong.csv", Pattern: "strong*.csv"},
+			seed: func(server *drivetest.Server, provider *gdrive.Provider) {
+				server.AddFile(drivetest.File{Name: "strong.csv", Data: []byte("first")})
+				server.AddFile(drivetest.File{Name: "strong (1).csv", Data: []byte("second")})
+				server.AddFile(drivetest.File{Name: "strong notes.txt", Data: []byte("notes")})
+			},
+			want: "second",
+		},
+		{
+			name:     "folder path",
+			provider: gdrive.Provider{DataPath: "strong.csv", FolderPath: "Backups/Strong"},
+			seed: func(server *drivetest.Server, provider *gdrive.Provider) {
+				backups := server.AddFolder("Backups", "")
+				strongFolder := server.AddFolder("Strong", backups.ID)
+				server.AddFile(drivetest.File{Name: "strong.csv", Parents: []string{strongFolder.ID}, Data: []byte("in folder")})
+				server.AddFile(drivetest.File{Name: "strong.csv", Data: []byte("in my drive")})
+			},
+			want: "in folder",
+		},
+		{
+			name:     "missing folder",
+			provider: gdrive.Provider{DataPath: "strong.csv", FolderPath: "Backups/Strong"},
+			seed: func(server *drivetest.Server, provider *gdrive.Provider) {
+				server.AddFolder("Backups", "")
+				server.AddFile(drivetest.File{Name: "strong.csv", Data: []byte("in my drive")})
+			},
+			wantErr: true,
+		},
+		{
+			name:     "folder id",
+			provider: gdrive.Provider{DataPath: "strong.csv"},
+			seed: func(server *drivetest.Server, provider *gdrive.Provider) {
+				folder := server.AddFolder("Strong", "")
+				provider.FolderID = folder.ID
+				server.AddFile(drivetest.File{Name: "strong.csv", Parents: []string{folder.ID}, Data: []byte("in folder")})
+				server.AddFile(drivetest.File{Name: "strong.csv", Data: []byte("in my drive")})
+			},
+			want: "in folder",
+		},
+		{
+			name:     "no files",
+			provider: gdrive.Provider{DataPath: "strong.csv"},
+			seed:     func(server *drivetest.Server, provider *gdrive.Provider) {},
+			wantErr:  true,
+		},
+		{
+			name:     "list error",
+			provider: gdrive.Provider{DataPath: "strong.csv"},
+			seed: func(server *drivetest.Server, provider *gdrive.Provider) {
+				server.AddFile(drivetest.File{Name: "strong.csv", Data: []byte("data")})
+				server.FailNext(http.MethodGet, "/drive/v3/files", http.StatusForbidden, "Insufficient Permission")
+			},
+			wantErr: true,
+		},
+		{
+			name:     "download error",
+			provider: gdrive.Provider{DataPath: "strong.csv"},
+			seed: func(server *drivetest.Server, provider *gdrive.Provider) {
+				file := server.AddFile(drivetest.File{Name: "strong.csv", Data: []byte("data")})
+				server.FailNext(http.MethodGet, "/drive/v3/files/"+file.ID, http.StatusNotFound, "File not found")
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := drivetest.NewServer()
+			defer server.Close()
+
+			tt.seed(server, &tt.provider)
+
+			provider := newDriveService(t, server, &tt.provider)
+
+			got, err := openExport(context.Background(), provider)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestProvider_OpenState(t *testing.T) {
+	t.Parallel()
+
+	server := drivetest.NewServer()
+	defer server.Close()
+
+	export := server.AddFile(drivetest.File{Name: "strong.csv", Data: []byte("v1")})
+
+	provider := newDriveService(t, server, &gdrive.Provider{DataPath: "strong.csv", State: newStateFile(t)})
+	ctx := context.Background()
+
+	got, err := openExport(ctx, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "v1", string(got))
+
+	// Without a commit the same export is imported again.
+	got, err = openExport(ctx, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "v1", string(got))
+
+	if err := provider.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = openExport(ctx, provider)
+	assert.True(t, errors.Is(err, data.ErrNotModified), "committed export should not be imported again, got %v", err)
+
+	server.AddFile(drivetest.File{Name: "notes.txt", Data: []byte("unrelated")})
+
+	before := len(server.Requests())
+
+	_, err = openExport(ctx, provider)
+	assert.True(t, errors.Is(err, data.ErrNotModified), "unrelated change should be ignored, got %v", err)
+	assert.Equal(t, []string{"GET /drive/v3/changes"}, server.Requests()[before:], "unchanged export should only list changes")
+
+	server.UpdateFile(export.ID, []byte("v2"))
+
+	got, err = openExport(ctx, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "v2", string(got))
+
+	// Latest downloads regardless of state.
+	if err := provider.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = provider.Latest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "v2", string(got))
+}
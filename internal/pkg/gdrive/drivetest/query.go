@@ -0,0 +1,206 @@
+package drivetest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// matcher reports whether a file matches a files.list query.
+type matcher func(file File) bool
+
+// parseQuery parses the files.list query terms the gdrive package uses,
+// joined by "and": name = or contains, mimeType = or !=, trashed = and
+// 'id' in parents. Unsupported terms are errors, so tests notice queries the
+// fake would silently get wrong.
+func parseQuery(query string) (matcher, error) {
+	if strings.TrimSpace(query) == "" {
+		return func(File) bool { return true }, nil
+	}
+
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var terms []matcher
+
+	for len(tokens) > 0 {
+		if len(terms) > 0 {
+			if tokens[0].text != "and" || tokens[0].quoted {
+				return nil, fmt.Errorf("invalid query %q: expected and, got %q", query, tokens[0].text)
+			}
+
+			tokens = tokens[1:]
+		}
+
+		if len(tokens) < 3 {
+			return nil, fmt.Errorf("invalid query %q: incomplete term", query)
+		}
+
+		term, err := parseTerm(tokens[0], tokens[1], tokens[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid query %q: %w", query, err)
+		}
+
+		terms = append(terms, term)
+		tokens = tokens[3:]
+	}
+
+	return func(file File) bool {
+		for _, term := range terms {
+			if !term(file) {
+				return false
+			}
+		}
+
+		return true
+	}, nil
+}
+
+func parseTerm(left, operator, right token) (matcher, error) {
+	if left.quoted && operator.text == "in" && right.text == "parents" {
+		return func(file File) bool {
+			return slices.Contains(file.Parents, left.text)
+		}, nil
+	}
+
+	switch {
+	case left.text == "name" && operator.text == "=" && right.quoted:
+		return func(file File) bool { return file.Name == right.text }, nil
+	case left.text == "name" && operator.text == "contains" && right.quoted:
+		// Drive only prefix matches names.
+		return func(file File) bool { return strings.HasPrefix(file.Name, right.text) }, nil
+	case left.text == "mimeType" && operator.text == "=" && right.quoted:
+		return func(file File) bool { return file.MimeType == right.text }, nil
+	case left.text == "mimeType" && operator.text == "!=" && right.quoted:
+		return func(file File) bool { return file.MimeType != right.text }, nil
+	case left.text == "trashed" && operator.text == "=" && !right.quoted && (right.text == "true" || right.text == "false"):
+		trashed := right.text == "true"
+
+		return func(file File) bool { return file.Trashed == trashed }, nil
+	}
+
+	return nil, fmt.Errorf("unsupported term %s %s %s", left, operator, right)
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+func (t token) String() string {
+	if t.quoted {
+		return "'" + t.text + "'"
+	}
+
+	return t.text
+}
+
+// tokenize splits a query into words, operators and single quoted strings,
+// unescaping \' and \\ inside strings.
+func tokenize(query string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(query); {
+		switch c := query[i]; {
+		case c == ' ':
+			i++
+		case c == '\'':
+			var builder strings.Builder
+
+			i++
+
+			for {
+				if i >= len(query) {
+					return nil, fmt.Errorf("invalid query %q: unterminated string", query)
+				}
+
+				if query[i] == '\\' && i+1 < len(query) {
+					builder.WriteByte(query[i+1])
+					i += 2
+
+					continue
+				}
+
+				if query[i] == '\'' {
+					i++
+					break
+				}
+
+				builder.WriteByte(query[i])
+				i++
+			}
+
+			tokens = append(tokens, token{text: builder.String(), quoted: true})
+		case c == '!' && strings.HasPrefix(query[i:], "!="):
+			tokens = append(tokens, token{text: "!="})
+			i += 2
+		case c == '=':
+			tokens = append(tokens, token{text: "="})
+			i++
+		default:
+			end := strings.IndexAny(query[i:], " '=!")
+			if end == -1 {
+				end = len(query) - i
+			}
+
+			tokens = append(tokens, token{text: query[i : i+end]})
+			i += end
+		}
+	}
+
+	return tokens, nil
+}
+
+// parseOrderBy parses a comma separated orderBy of createdTime, modifiedTime
+// and name, each optionally followed by desc. Ties are broken by ID.
+func parseOrderBy(orderBy string) (func(a, b File) bool, error) {
+	type key struct {
+		compare func(a, b File) int
+		desc    bool
+	}
+
+	var keys []key
+
+	for _, field := range strings.Split(orderBy, ",") {
+		parts := strings.Fields(field)
+		if len(parts) == 0 {
+			continue
+		}
+
+		if len(parts) > 2 || (len(parts) == 2 && parts[1] != "desc") {
+			return nil, fmt.Errorf("invalid orderBy %q", orderBy)
+		}
+
+		var compare func(a, b File) int
+
+		switch parts[0] {
+		case "createdTime":
+			compare = func(a, b File) int { return a.CreatedTime.Compare(b.CreatedTime) }
+		case "modifiedTime":
+			compare = func(a, b File) int { return a.ModifiedTime.Compare(b.ModifiedTime) }
+		case "name":
+			compare = func(a, b File) int { return strings.Compare(a.Name, b.Name) }
+		default:
+			return nil, fmt.Errorf("unsupported orderBy field %q", parts[0])
+		}
+
+		keys = append(keys, key{compare: compare, desc: len(parts) == 2})
+	}
+
+	return func(a, b File) bool {
+		for _, k := range keys {
+			c := k.compare(a, b)
+			if k.desc {
+				c = -c
+			}
+
+			if c != 0 {
+				return c < 0
+			}
+		}
+
+		return a.ID < b.ID
+	}, nil
+}
@@ -0,0 +1,485 @@
+// Package drivetest provides an in-memory fake of the subset of the Google
+// Drive v3 API used by the gdrive package, so imports can be tested offline.
+package drivetest
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+const (
+	// FolderMimeType is the MIME type of Drive folders.
+	FolderMimeType = "application/vnd.google-apps.folder"
+	// RootID is the alias of the My Drive folder.
+	RootID = "root"
+
+	basePath         = "/drive/v3/"
+	filesPath        = basePath + "files"
+	changesPath      = basePath + "changes"
+	startTokenPath   = changesPath + "/startPageToken"
+	defaultPageSize  = 100
+	csvMimeType      = "text/csv"
+	modifiedTimeStep = time.Minute
+)
+
+// File is the fake server's representation of a Drive file or folder.
+// Parents defaults to My Drive.
+type File struct {
+	ID           string
+	Name         string
+	MimeType     string
+	Parents      []string
+	CreatedTime  time.Time
+	ModifiedTime time.Time
+	Trashed      bool
+	Data         []byte
+}
+
+// md5Checksum is only reported for files with content, like Drive.
+func (f File) md5Checksum() string {
+	if f.MimeType == FolderMimeType {
+		return ""
+	}
+
+	sum := md5.Sum(f.Data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+type fault struct {
+	status int
+	body   string
+}
+
+// Server is an httptest.Server that behaves like the Drive v3 API. Every
+// added or changed file advances a fake clock by a minute, so files added
+// later are modified later.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	files    map[string]*File
+	nextID   int
+	now      time.Time
+	changes  []string // IDs of changed files, in order
+	faults   map[string][]fault
+	requests []string
+}
+
+// NewServer starts a fake Drive server. Callers must Close it.
+func NewServer() *Server {
+	s := &Server{
+		files:  make(map[string]*File),
+		nextID: 1,
+		now:    time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		faults: make(map[string][]fault),
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// Service returns a drive.Service that talks to the fake server.
+func (s *Server) Service(ctx context.Context) (*drive.Service, error) {
+	return drive.NewService(ctx, option.WithEndpoint(s.URL+basePath), option.WithHTTPClient(s.Client()))
+}
+
+// AddFile seeds a file and returns it with its assigned ID and times.
+func (s *Server) AddFile(file File) File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file.ID = fmt.Sprintf("file%d", s.nextID)
+	s.nextID++
+
+	if file.MimeType == "" {
+		file.MimeType = csvMimeType
+	}
+
+	if len(file.Parents) == 0 {
+		file.Parents = []string{RootID}
+	}
+
+	s.tick()
+
+	if file.CreatedTime.IsZero() {
+		file.CreatedTime = s.now
+	}
+
+	if file.ModifiedTime.IsZero() {
+		file.ModifiedTime = s.now
+	}
+
+	s.files[file.ID] = &file
+	s.changes = append(s.changes, file.ID)
+
+	return file
+}
+
+// AddFolder seeds a folder named name inside parent, or My Drive if parent
+// is empty.
+func (s *Server) AddFolder(name, parent string) File {
+	folder := File{Name: name, MimeType: FolderMimeType}
+
+	if parent != "" {
+		folder.Parents = []string{parent}
+	}
+
+	return s.AddFile(folder)
+}
+
+// UpdateFile replaces the contents of the file with id, as re-exporting over
+// it in the Strong app would.
+func (s *Server) UpdateFile(id string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, ok := s.files[id]
+	if !ok {
+		panic("drivetest: no file " + id)
+	}
+
+	s.tick()
+
+	file.Data = data
+	file.ModifiedTime = s.now
+	s.changes = append(s.changes, id)
+}
+
+// TrashFile moves the file with id to the trash.
+func (s *Server) TrashFile(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, ok := s.files[id]
+	if !ok {
+		panic("drivetest: no file " + id)
+	}
+
+	s.tick()
+
+	file.Trashed = true
+	file.ModifiedTime = s.now
+	s.changes = append(s.changes, id)
+}
+
+// File returns a snapshot of the file with id.
+func (s *Server) File(id string) (File, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, ok := s.files[id]
+	if !ok {
+		return File{}, false
+	}
+
+	return *file, true
+}
+
+// Requests returns every request received as "METHOD /path", without the
+// query.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+// FailNext makes the next request matching method and path, such as
+// "/drive/v3/files", respond with status and message instead of being
+// handled. Faults queue up in call order.
+func (s *Server) FailNext(method, path string, status int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := method + " " + path
+	s.faults[key] = append(s.faults[key], fault{status: status, body: message})
+}
+
+func (s *Server) tick() {
+	s.now = s.now.Add(modifiedTimeStep)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	key := r.Method + " " + r.URL.Path
+	if queued := s.faults[key]; len(queued) > 0 {
+		s.faults[key] = queued[1:]
+		writeError(w, queued[0].status, queued[0].body)
+		return
+	}
+
+	switch {
+	case r.URL.Path == filesPath && r.Method == http.MethodGet:
+		s.listFiles(w, r)
+	case strings.HasPrefix(r.URL.Path, filesPath+"/") && r.Method == http.MethodGet:
+		s.getFile(w, r)
+	case strings.HasPrefix(r.URL.Path, filesPath+"/") && r.Method == http.MethodPatch:
+		s.updateFile(w, r)
+	case r.URL.Path == startTokenPath && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{"startPageToken": strconv.Itoa(len(s.changes))})
+	case r.URL.Path == changesPath && r.Method == http.MethodGet:
+		s.listChanges(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (s *Server) listFiles(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	match, err := parseQuery(query.Get("q"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	less, err := parseOrderBy(query.Get("orderBy"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pageSize, err := intParam(query.Get("pageSize"), defaultPageSize)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	offset, err := tokenParam(query.Get("pageToken"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	files := make([]File, 0)
+
+	for _, file := range s.files {
+		if match(*file) {
+			files = append(files, *file)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return less(files[i], files[j])
+	})
+
+	response := map[string]any{"kind": "drive#fileList"}
+
+	if offset > len(files) {
+		offset = len(files)
+	}
+
+	end := offset + pageSize
+	if end < len(files) {
+		response["nextPageToken"] = strconv.Itoa(end)
+	} else {
+		end = len(files)
+	}
+
+	page := make([]map[string]any, 0, end-offset)
+	for _, file := range files[offset:end] {
+		page = append(page, fileJSON(file))
+	}
+
+	response["files"] = page
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) getFile(w http.ResponseWriter, r *http.Request) {
+	file, ok := s.files[strings.TrimPrefix(r.URL.Path, filesPath+"/")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "File not found")
+		return
+	}
+
+	if r.URL.Query().Get("alt") != "media" {
+		writeJSON(w, http.StatusOK, fileJSON(*file))
+		return
+	}
+
+	if file.MimeType == FolderMimeType {
+		writeError(w, http.StatusForbidden, "Only files with binary content can be downloaded")
+		return
+	}
+
+	w.Header().Set("Content-Type", file.MimeType)
+	w.Write(file.Data)
+}
+
+// updateFile only supports moving files with addParents and removeParents.
+func (s *Server) updateFile(w http.ResponseWriter, r *http.Request) {
+	file, ok := s.files[strings.TrimPrefix(r.URL.Path, filesPath+"/")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "File not found")
+		return
+	}
+
+	query := r.URL.Query()
+
+	parents := make([]string, 0, len(file.Parents))
+
+	for _, parent := range file.Parents {
+		if !containsID(query.Get("removeParents"), parent) {
+			parents = append(parents, parent)
+		}
+	}
+
+	for _, parent := range strings.Split(query.Get("addParents"), ",") {
+		if parent == "" || containsID(strings.Join(parents, ","), parent) {
+			continue
+		}
+
+		if _, ok := s.files[parent]; !ok && parent != RootID {
+			writeError(w, http.StatusNotFound, "File not found: "+parent)
+			return
+		}
+
+		parents = append(parents, parent)
+	}
+
+	file.Parents = parents
+	s.changes = append(s.changes, file.ID)
+
+	writeJSON(w, http.StatusOK, fileJSON(*file))
+}
+
+func (s *Server) listChanges(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	offset, err := tokenParam(query.Get("pageToken"))
+	if err != nil || query.Get("pageToken") == "" || offset > len(s.changes) {
+		writeError(w, http.StatusBadRequest, "Invalid Value: pageToken")
+		return
+	}
+
+	pageSize, err := intParam(query.Get("pageSize"), defaultPageSize)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := map[string]any{"kind": "drive#changeList"}
+
+	end := offset + pageSize
+	if end < len(s.changes) {
+		response["nextPageToken"] = strconv.Itoa(end)
+	} else {
+		end = len(s.changes)
+		response["newStartPageToken"] = strconv.Itoa(end)
+	}
+
+	changes := make([]map[string]any, 0, end-offset)
+
+	for _, id := range s.changes[offset:end] {
+		file, ok := s.files[id]
+
+		item := map[string]any{
+			"kind":       "drive#change",
+			"changeType": "file",
+			"fileId":     id,
+			"removed":    !ok,
+		}
+
+		if ok {
+			item["file"] = fileJSON(*file)
+		}
+
+		changes = append(changes, item)
+	}
+
+	response["changes"] = changes
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func fileJSON(file File) map[string]any {
+	item := map[string]any{
+		"kind":         "drive#file",
+		"id":           file.ID,
+		"name":         file.Name,
+		"mimeType":     file.MimeType,
+		"parents":      file.Parents,
+		"trashed":      file.Trashed,
+		"createdTime":  file.CreatedTime.Format(time.RFC3339Nano),
+		"modifiedTime": file.ModifiedTime.Format(time.RFC3339Nano),
+	}
+
+	if checksum := file.md5Checksum(); checksum != "" {
+		item["md5Checksum"] = checksum
+		item["size"] = strconv.Itoa(len(file.Data))
+	}
+
+	return item
+}
+
+func containsID(ids, id string) bool {
+	for _, candidate := range strings.Split(ids, ",") {
+		if candidate == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func intParam(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid parameter value %q", value)
+	}
+
+	return n, nil
+}
+
+func tokenParam(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid page token %q", value)
+	}
+
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{
+			"code":    status,
+			"message": message,
+			"errors":  []any{map[string]any{"message": message}},
+		},
+	})
+}
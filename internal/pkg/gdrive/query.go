@@ -0,0 +1,118 @@
+package gdrive
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	folderMimeType = "application/vnd.google-apps.folder"
+	rootFolderID   = "root"
+	globMeta       = `*?[\`
+)
+
+// namePattern is the glob export names must match.
+func (p *Provider) namePattern() string {
+	if p.Pattern != "" {
+		return p.Pattern
+	}
+
+	return path.Base(p.DataPath)
+}
+
+// matches reports whether name is an export name.
+func (p *Provider) matches(name string) bool {
+	if p.Pattern == "" {
+		return name == path.Base(p.DataPath)
+	}
+
+	ok, err := path.Match(p.Pattern, name)
+
+	return err == nil && ok
+}
+
+// query builds the files.list query for exports: not trashed, not folders,
+// inside the configured folder and named like the export. Glob patterns are
+// narrowed to their literal prefix and matched in full by matches.
+func (p *Provider) query(ctx context.Context) (string, error) {
+	if p.Pattern != "" {
+		if _, err := path.Match(p.Pattern, ""); err != nil {
+			return "", fmt.Errorf("error invalid drive file pattern %q: %w", p.Pattern, err)
+		}
+	}
+
+	clauses := []string{
+		"trashed = false",
+		fmt.Sprintf("mimeType != '%s'", folderMimeType),
+	}
+
+	folderID, err := p.folderID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if folderID != "" {
+		clauses = append(clauses, fmt.Sprintf("'%s' in parents", escape(folderID)))
+	}
+
+	switch {
+	case p.Pattern == "":
+		clauses = append(clauses, fmt.Sprintf("name = '%s'", escape(path.Base(p.DataPath))))
+	case !strings.ContainsAny(p.Pattern, globMeta):
+		clauses = append(clauses, fmt.Sprintf("name = '%s'", escape(p.Pattern)))
+	default:
+		if prefix := p.Pattern[:strings.IndexAny(p.Pattern, globMeta)]; prefix != "" {
+			clauses = append(clauses, fmt.Sprintf("name contains '%s'", escape(prefix)))
+		}
+	}
+
+	return strings.Join(clauses, " and "), nil
+}
+
+// folderID returns the configured folder ID, resolving FolderPath one
+// folder at a time from My Drive on first use.
+func (p *Provider) folderID(ctx context.Context) (string, error) {
+	if p.FolderID != "" {
+		return p.FolderID, nil
+	}
+
+	if p.FolderPath == "" {
+		return "", nil
+	}
+
+	if p.resolvedFolderID != "" {
+		return p.resolvedFolderID, nil
+	}
+
+	parent := rootFolderID
+
+	for _, name := range strings.Split(strings.Trim(p.FolderPath, "/"), "/") {
+		if name == "" {
+			continue
+		}
+
+		query := fmt.Sprintf("name = '%s' and mimeType = '%s' and '%s' in parents and trashed = false", escape(name), folderMimeType, escape(parent))
+
+		folders, err := p.DriveService.Files.List().PageSize(1).Q(query).Fields("files(id)").Context(ctx).Do()
+		if err != nil {
+			return "", fmt.Errorf("error finding drive folder %s: %w", name, err)
+		}
+
+		if len(folders.Files) == 0 {
+			return "", fmt.Errorf("error drive folder %s not found in %s", name, p.FolderPath)
+		}
+
+		parent = folders.Files[0].Id
+	}
+
+	p.resolvedFolderID = parent
+
+	return parent, nil
+}
+
+// escape quotes value for use inside a single quoted query string.
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+}
@@ -0,0 +1,106 @@
+package gdrive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// State is what the provider remembers about the last export it imported.
+type State struct {
+	FileID       string `json:"file_id"`
+	ModifiedTime string `json:"modified_time"`
+	MD5Checksum  string `json:"md5_checksum"`
+	// PageToken is the Drive changes API token to list changes after the
+	// import from.
+	PageToken string `json:"page_token"`
+	// Processed maps the file IDs of exports handled by ImportAll to their
+	// version, so edited exports are processed again.
+	Processed map[string]string `json:"processed,omitempty"`
+}
+
+// Same reports whether file is the export the state was recorded for,
+// unchanged.
+func (state State) Same(fileID, modifiedTime, md5Checksum string) bool {
+	return state.FileID != "" &&
+		state.FileID == fileID &&
+		state.ModifiedTime == modifiedTime &&
+		state.MD5Checksum == md5Checksum
+}
+
+// processed reports whether file was handled by ImportAll at its current
+// version.
+func (state State) processed(file *drive.File) bool {
+	version, ok := state.Processed[file.Id]
+
+	return ok && version == fileVersion(file)
+}
+
+// fileVersion identifies the contents of file, falling back to its modified
+// time for files Drive has no checksum for.
+func fileVersion(file *drive.File) string {
+	if file.Md5Checksum != "" {
+		return file.Md5Checksum
+	}
+
+	return file.ModifiedTime
+}
+
+// StateFile persists State as JSON between runs.
+type StateFile struct {
+	path string
+	mu   sync.RWMutex
+}
+
+func NewStateFile(path string) (*StateFile, error) {
+	if path == "" {
+		return nil, errors.New("drive state path is required")
+	}
+
+	return &StateFile{path: path}, nil
+}
+
+// Load returns the saved state, or the zero State if none has been saved.
+func (f *StateFile) Load() (State, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var state State
+
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+
+	if err != nil {
+		return state, fmt.Errorf("error reading drive state %s: %w", f.path, err)
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("error unmarshling drive state %s: %w", f.path, err)
+	}
+
+	return state, nil
+}
+
+// Save replaces the saved state.
+func (f *StateFile) Save(state State) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error marshling drive state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return fmt.Errorf("error creating drive state directory: %w", err)
+	}
+
+	return os.WriteFile(f.path, data, 0600)
+}
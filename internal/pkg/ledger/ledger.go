@@ -0,0 +1,185 @@
+// Package ledger records which workouts have been synced to which
+// destination, so uploads are idempotent and can be audited.
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Entry is the sync state of one workout at one destination.
+type Entry struct {
+	WorkoutKey  string     `json:"workout_key"`
+	ContentHash string     `json:"content_hash"`
+	Destination string     `json:"destination"`
+	RemoteID    string     `json:"remote_id,omitempty"`
+	UploadedAt  *time.Time `json:"uploaded_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Uploaded reports whether the workout exists at the destination.
+func (entry Entry) Uploaded() bool {
+	return entry.RemoteID != ""
+}
+
+// Ledger is a JSON file of entries keyed by destination and workout key.
+type Ledger struct {
+	path    string
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+// Open loads the ledger at path, starting empty if the file does not exist.
+func Open(path string) (*Ledger, error) {
+	if path == "" {
+		return nil, errors.New("ledger path is required")
+	}
+
+	l := &Ledger{path: path, entries: make(map[string]Entry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading ledger %s: %w", path, err)
+	}
+
+	var entries []Entry
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("error unmarshling ledger %s: %w", path, err)
+	}
+
+	for _, entry := range entries {
+		l.entries[key(entry.Destination, entry.WorkoutKey)] = entry
+	}
+
+	return l, nil
+}
+
+// Lookup returns the entry for the workout at destination.
+func (l *Ledger) Lookup(destination, workoutKey string) (Entry, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	entry, ok := l.entries[key(destination, workoutKey)]
+
+	return entry, ok
+}
+
+// RecordUpload marks the workout as uploaded to destination as remoteID,
+// clearing any previous error.
+func (l *Ledger) RecordUpload(destination, workoutKey, contentHash, remoteID string) error {
+	now := time.Now().UTC()
+
+	return l.update(destination, workoutKey, func(entry *Entry) {
+		entry.ContentHash = contentHash
+		entry.RemoteID = remoteID
+		entry.UploadedAt = &now
+		entry.LastError = ""
+		entry.UpdatedAt = now
+	})
+}
+
+// RecordError stores the error of a failed sync. A previous upload's remote
+// ID and content hash are kept.
+func (l *Ledger) RecordError(destination, workoutKey, contentHash string, syncErr error) error {
+	return l.update(destination, workoutKey, func(entry *Entry) {
+		if !entry.Uploaded() {
+			entry.ContentHash = contentHash
+		}
+
+		entry.LastError = syncErr.Error()
+		entry.UpdatedAt = time.Now().UTC()
+	})
+}
+
+// Remove deletes the workout's entry at destination.
+func (l *Ledger) Remove(destination, workoutKey string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key(destination, workoutKey))
+
+	return l.save()
+}
+
+// Entries returns all entries ordered by destination and workout key.
+func (l *Ledger) Entries() []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.sorted()
+}
+
+func (l *Ledger) update(destination, workoutKey string, fn func(entry *Entry)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	k := key(destination, workoutKey)
+
+	entry, ok := l.entries[k]
+	if !ok {
+		entry = Entry{WorkoutKey: workoutKey, Destination: destination}
+	}
+
+	fn(&entry)
+	l.entries[k] = entry
+
+	return l.save()
+}
+
+// save writes the ledger to a temporary file and renames it into place so a
+// crash never leaves a truncated ledger. Callers must hold the write lock.
+func (l *Ledger) save() error {
+	data, err := json.MarshalIndent(l.sorted(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshling ledger: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("error creating ledger directory: %w", err)
+	}
+
+	tmp := l.path + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("error writing ledger: %w", err)
+	}
+
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("error replacing ledger: %w", err)
+	}
+
+	return nil
+}
+
+func (l *Ledger) sorted() []Entry {
+	entries := make([]Entry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Destination != entries[j].Destination {
+			return entries[i].Destination < entries[j].Destination
+		}
+
+		return entries[i].WorkoutKey < entries[j].WorkoutKey
+	})
+
+	return entries
+}
+
+func key(destination, workoutKey string) string {
+	return destination + "/" + workoutKey
+}
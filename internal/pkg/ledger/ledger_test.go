@@ -0,0 +1,105 @@
+package ledger_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adiazny/strong/internal/pkg/ledger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedger(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "strong", "ledger.json")
+
+	l, err := ledger.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, ok := l.Lookup("strava", "20221114T071524")
+	assert.False(t, ok, "empty ledger should have no entries")
+
+	if err := l.RecordError("strava", "20221114T071524", "hash-1", errors.New("error response status code is 500")); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, _ := l.Lookup("strava", "20221114T071524")
+	assert.False(t, entry.Uploaded())
+	assert.Equal(t, "hash-1", entry.ContentHash)
+	assert.Equal(t, "error response status code is 500", entry.LastError)
+	assert.Nil(t, entry.UploadedAt, "a failed sync should not record an upload time")
+
+	if err := l.RecordUpload("strava", "20221114T071524", "hash-1", "42"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.RecordUpload("strava", "20221116T065438", "hash-2", "43"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.RecordError("strava", "20221114T071524", "hash-3", errors.New("error response status code is 429")); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := ledger.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := reopened.Entries()
+	assert.Len(t, entries, 2)
+
+	entry = entries[0]
+	assert.Equal(t, "20221114T071524", entry.WorkoutKey)
+	assert.Equal(t, "42", entry.RemoteID, "an error should keep the earlier upload")
+	assert.Equal(t, "hash-1", entry.ContentHash, "an error should keep the uploaded content hash")
+	assert.Equal(t, "error response status code is 429", entry.LastError)
+	assert.NotNil(t, entry.UploadedAt)
+
+	if err := reopened.Remove("strava", "20221114T071524"); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, reopened.Entries(), 1)
+
+	_, err = os.Stat(path + ".tmp")
+	assert.ErrorIs(t, err, os.ErrNotExist, "temporary file should be renamed into place")
+}
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	corrupt := filepath.Join(dir, "corrupt.json")
+	if err := os.WriteFile(corrupt, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "missing file", path: filepath.Join(dir, "missing.json")},
+		{name: "corrupt file", path: corrupt, wantErr: true},
+		{name: "empty path", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ledger.Open(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
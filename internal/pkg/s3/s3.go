@@ -0,0 +1,221 @@
+// Package s3 is a small client for S3 compatible object storage such as
+// MinIO, used to read Strong exports from a bucket and archive them there.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	service        = "s3"
+	DefaultRegion  = "us-east-1"
+	listObjectsMax = 1000
+)
+
+// ErrNotFound is returned for missing buckets and keys.
+var ErrNotFound = errors.New("s3 object not found")
+
+// Object describes a stored object.
+type Object struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	ETag         string    `xml:"ETag"`
+	Size         int64     `xml:"Size"`
+}
+
+// Checksum is the ETag without quotes, the MD5 of objects not uploaded in
+// parts.
+func (object Object) Checksum() string {
+	return strings.Trim(object.ETag, `"`)
+}
+
+// Client talks to one S3 endpoint with path style URLs, which MinIO needs.
+type Client struct {
+	endpoint   *url.URL
+	signer     Signer
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+type Option func(c *Client)
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithRegion sets the signing region, DefaultRegion if unset.
+func WithRegion(region string) Option {
+	return func(c *Client) {
+		c.signer.Region = region
+	}
+}
+
+func NewClient(endpoint, accessKey, secretKey string, opts ...Option) (*Client, error) {
+	if endpoint == "" {
+		return nil, errors.New("s3 endpoint is required")
+	}
+
+	if accessKey == "" || secretKey == "" {
+		return nil, errors.New("s3 access key and secret key are required")
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("error invalid s3 endpoint %q", endpoint)
+	}
+
+	c := &Client{
+		endpoint:   u,
+		signer:     Signer{AccessKey: accessKey, SecretKey: secretKey, Region: DefaultRegion, Service: service},
+		httpClient: http.DefaultClient,
+		now:        time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+type listBucketResult struct {
+	Contents              []Object `xml:"Contents"`
+	IsTruncated           bool     `xml:"IsTruncated"`
+	NextContinuationToken string   `xml:"NextContinuationToken"`
+}
+
+// ListObjects lists every object in bucket whose key starts with prefix,
+// following ListObjectsV2 continuation tokens.
+func (c *Client) ListObjects(ctx context.Context, bucket, prefix string) ([]Object, error) {
+	objects := make([]Object, 0)
+	token := ""
+
+	for {
+		query := url.Values{
+			"list-type": {"2"},
+			"max-keys":  {strconv.Itoa(listObjectsMax)},
+		}
+
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		response, err := c.do(ctx, http.MethodGet, bucket, "", query, nil, "")
+		if err != nil {
+			return nil, fmt.Errorf("error listing s3 bucket %s: %w", bucket, err)
+		}
+
+		var result listBucketResult
+
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("error decoding s3 bucket list: %w", err)
+		}
+
+		objects = append(objects, result.Contents...)
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+
+		token = result.NextContinuationToken
+	}
+}
+
+// GetObject downloads the object at key.
+func (c *Client) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	response, err := c.do(ctx, http.MethodGet, bucket, key, nil, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("error getting s3 object %s: %w", key, err)
+	}
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading s3 object %s: %w", key, err)
+	}
+
+	return body, nil
+}
+
+// PutObject stores body at key.
+func (c *Client) PutObject(ctx context.Context, bucket, key string, body []byte, contentType string) error {
+	response, err := c.do(ctx, http.MethodPut, bucket, key, nil, body, contentType)
+	if err != nil {
+		return fmt.Errorf("error putting s3 object %s: %w", key, err)
+	}
+
+	response.Body.Close()
+
+	return nil
+}
+
+type errorResponse struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// do sends a signed request, turning non 2xx responses into errors.
+func (c *Client) do(ctx context.Context, method, bucket, key string, query url.Values, body []byte, contentType string) (*http.Response, error) {
+	u := *c.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + bucket
+
+	if key != "" {
+		u.Path += "/" + key
+	}
+
+	u.RawPath = encode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	c.signer.Sign(req, hashHex(body), c.now())
+
+	response, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return response, nil
+	}
+
+	defer response.Body.Close()
+
+	var s3Err errorResponse
+
+	xml.NewDecoder(io.LimitReader(response.Body, 1<<16)).Decode(&s3Err)
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, s3Err.Code)
+	}
+
+	return nil, fmt.Errorf("error s3 status %d: %s %s", response.StatusCode, s3Err.Code, s3Err.Message)
+}
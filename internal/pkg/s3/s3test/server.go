@@ -0,0 +1,290 @@
+// Package s3test provides an in-memory fake of the subset of the S3 API used
+// by the s3 package, standing in for MinIO so imports can be tested offline.
+package s3test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/s3"
+)
+
+const (
+	// AccessKey and SecretKey are the only credentials the server accepts.
+	AccessKey = "minioadmin"
+	SecretKey = "minio-secret"
+
+	defaultMaxKeys = 1000
+	timeFormat     = "2006-01-02T15:04:05.000Z"
+	modifiedStep   = time.Minute
+)
+
+type object struct {
+	data         []byte
+	lastModified time.Time
+}
+
+// Server is an httptest.Server that behaves like an S3 endpoint with path
+// style URLs. Every stored object advances a fake clock by a minute, so
+// objects put later are modified later.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	buckets  map[string]map[string]*object
+	now      time.Time
+	pageSize int
+	requests []string
+}
+
+// NewServer starts a fake S3 server. Callers must Close it.
+func NewServer() *Server {
+	s := &Server{
+		buckets:  make(map[string]map[string]*object),
+		now:      time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		pageSize: defaultMaxKeys,
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// NewClient returns an s3.Client with the server's credentials.
+func (s *Server) NewClient() (*s3.Client, error) {
+	return s3.NewClient(s.URL, AccessKey, SecretKey, s3.WithHTTPClient(s.Client()))
+}
+
+// AddBucket creates an empty bucket.
+func (s *Server) AddBucket(bucket string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = make(map[string]*object)
+	}
+}
+
+// PutObject stores data at key, creating the bucket if needed.
+func (s *Server) PutObject(bucket, key string, data []byte) {
+	s.AddBucket(bucket)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.put(bucket, key, data)
+}
+
+// Object returns the data stored at key.
+func (s *Server) Object(bucket, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.buckets[bucket][key]
+	if !ok {
+		return nil, false
+	}
+
+	return obj.data, true
+}
+
+// Keys returns the keys in bucket, sorted.
+func (s *Server) Keys(bucket string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.buckets[bucket]))
+	for key := range s.buckets[bucket] {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// SetPageSize caps the keys listed per page below max-keys, to exercise
+// continuation tokens.
+func (s *Server) SetPageSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pageSize = n
+}
+
+// Requests returns every request received as "METHOD /path", in order.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) put(bucket, key string, data []byte) {
+	s.now = s.now.Add(modifiedStep)
+	s.buckets[bucket][key] = &object{data: append([]byte(nil), data...), lastModified: s.now}
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	if !authorized(r, body) {
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch", "bad credentials or payload hash")
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	objects, ok := s.buckets[bucket]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		s.list(w, r, objects)
+	case r.Method == http.MethodGet:
+		obj, ok := objects[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist")
+			return
+		}
+
+		w.Header().Set("ETag", etag(obj.data))
+		w.Header().Set("Last-Modified", obj.lastModified.Format(http.TimeFormat))
+		w.Write(obj.data)
+	case r.Method == http.MethodPut && key != "":
+		s.put(bucket, key, body)
+		w.Header().Set("ETag", etag(body))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method+" is not supported")
+	}
+}
+
+// authorized checks the credential and payload hash of a signed request.
+// Signatures themselves are not verified.
+func authorized(r *http.Request, body []byte) bool {
+	if !strings.Contains(r.Header.Get("Authorization"), "Credential="+AccessKey+"/") {
+		return false
+	}
+
+	sum := sha256.Sum256(body)
+
+	return r.Header.Get("X-Amz-Content-Sha256") == hex.EncodeToString(sum[:])
+}
+
+type listContents struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int    `xml:"Size"`
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	Prefix                string         `xml:"Prefix"`
+	KeyCount              int            `xml:"KeyCount"`
+	MaxKeys               int            `xml:"MaxKeys"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	Contents              []listContents `xml:"Contents"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+}
+
+// list implements ListObjectsV2. Continuation tokens are the last key
+// returned.
+func (s *Server) list(w http.ResponseWriter, r *http.Request, objects map[string]*object) {
+	query := r.URL.Query()
+
+	if query.Get("list-type") != "2" {
+		writeError(w, http.StatusBadRequest, "InvalidArgument", "only ListObjectsV2 is supported")
+		return
+	}
+
+	maxKeys := s.pageSize
+
+	if value := query.Get("max-keys"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "InvalidArgument", "invalid max-keys")
+			return
+		}
+
+		maxKeys = min(n, s.pageSize)
+	}
+
+	prefix := query.Get("prefix")
+	after := query.Get("continuation-token")
+
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		if strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	result := listBucketResult{Prefix: prefix, MaxKeys: maxKeys}
+
+	if len(keys) > maxKeys {
+		keys = keys[:maxKeys]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[len(keys)-1]
+	}
+
+	for _, key := range keys {
+		obj := objects[key]
+		result.Contents = append(result.Contents, listContents{
+			Key:          key,
+			LastModified: obj.lastModified.Format(timeFormat),
+			ETag:         etag(obj.data),
+			Size:         len(obj.data),
+		})
+	}
+
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(result)
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+type errorResponse struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(errorResponse{Code: code, Message: message})
+}
@@ -0,0 +1,179 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/data"
+)
+
+const (
+	DefaultPattern = "*.csv"
+	csvContentType = "text/csv"
+
+	archiveTimeFormat = "20060102T150405Z"
+	archiveHashLength = 12
+	archiveMarkerDir  = "sha256/"
+)
+
+// Source is the newest Strong export under Prefix in Bucket whose base name
+// matches Pattern, DefaultPattern if empty. Exports already committed are
+// not opened again until a newer one is uploaded; this is kept in memory,
+// so the newest export is imported once more after a restart.
+type Source struct {
+	Client  *Client
+	Bucket  string
+	Prefix  string
+	Pattern string
+
+	mu        sync.Mutex
+	committed data.Metadata
+	pending   data.Metadata
+}
+
+func (s *Source) Open(ctx context.Context) (io.ReadCloser, data.Metadata, error) {
+	object, err := s.latest(ctx)
+	if err != nil {
+		return nil, data.Metadata{}, err
+	}
+
+	metadata := data.Metadata{
+		Name:         object.Key,
+		Location:     "s3://" + s.Bucket + "/" + object.Key,
+		ModifiedTime: object.LastModified,
+		Checksum:     object.Checksum(),
+	}
+
+	s.mu.Lock()
+	committed := s.committed
+	s.mu.Unlock()
+
+	if metadata.Name == committed.Name && metadata.Checksum == committed.Checksum {
+		return nil, metadata, data.ErrNotModified
+	}
+
+	body, err := s.Client.GetObject(ctx, s.Bucket, object.Key)
+	if err != nil {
+		return nil, metadata, err
+	}
+
+	s.mu.Lock()
+	s.pending = metadata
+	s.mu.Unlock()
+
+	return io.NopCloser(bytes.NewReader(body)), metadata, nil
+}
+
+// Commit marks the export last opened as synced.
+func (s *Source) Commit(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending.Name != "" {
+		s.committed = s.pending
+	}
+
+	return nil
+}
+
+func (s *Source) latest(ctx context.Context) (Object, error) {
+	pattern := s.Pattern
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+
+	objects, err := s.Client.ListObjects(ctx, s.Bucket, s.Prefix)
+	if err != nil {
+		return Object{}, err
+	}
+
+	var latest Object
+
+	for _, object := range objects {
+		if strings.HasSuffix(object.Key, "/") {
+			continue
+		}
+
+		if ok, _ := path.Match(pattern, path.Base(object.Key)); !ok {
+			continue
+		}
+
+		if latest.Key == "" || object.LastModified.After(latest.LastModified) {
+			latest = object
+		}
+	}
+
+	if latest.Key == "" {
+		return Object{}, fmt.Errorf("error no strong exports found in s3 bucket %s/%s", s.Bucket, s.Prefix)
+	}
+
+	return latest, nil
+}
+
+// Archiver stores every raw export imported under Prefix in Bucket, keyed by
+// the time it was archived, its content hash and its name, so that no copy
+// is overwritten. An empty marker object at sha256/<hash> under Prefix
+// records each hash archived, so the same content is only stored once.
+type Archiver struct {
+	Client *Client
+	Bucket string
+	Prefix string
+
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+func (a *Archiver) Archive(ctx context.Context, metadata data.Metadata, body []byte) error {
+	marker := a.prefix() + archiveMarkerDir + hashHex(body)
+
+	_, err := a.Client.GetObject(ctx, a.Bucket, marker)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("error checking s3 archive for %s: %w", metadata.Name, err)
+	}
+
+	if err := a.Client.PutObject(ctx, a.Bucket, a.key(metadata, body), body, csvContentType); err != nil {
+		return fmt.Errorf("error archiving %s to s3: %w", metadata.Name, err)
+	}
+
+	// The marker goes last, so a failure never hides an export not stored.
+	if err := a.Client.PutObject(ctx, a.Bucket, marker, nil, ""); err != nil {
+		return fmt.Errorf("error archiving %s to s3: %w", metadata.Name, err)
+	}
+
+	return nil
+}
+
+// key is the object key an export archived now is stored at, such as
+// archive/20221114T071524Z-9f86d081884c-strong.csv.
+func (a *Archiver) key(metadata data.Metadata, body []byte) string {
+	now := time.Now
+	if a.Now != nil {
+		now = a.Now
+	}
+
+	name := path.Base(strings.ReplaceAll(metadata.Name, `\`, "/"))
+	if name == "." || name == "/" {
+		name = "strong.csv"
+	}
+
+	return a.prefix() + now().UTC().Format(archiveTimeFormat) + "-" + hashHex(body)[:archiveHashLength] + "-" + name
+}
+
+func (a *Archiver) prefix() string {
+	if a.Prefix != "" && !strings.HasSuffix(a.Prefix, "/") {
+		return a.Prefix + "/"
+	}
+
+	return a.Prefix
+}
@@ -0,0 +1,133 @@
+package s3_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/data"
+	"github.com/adiazny/strong/internal/pkg/s3"
+	"github.com/adiazny/strong/internal/pkg/s3/s3test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSource_Open(t *testing.T) {
+	t.Parallel()
+
+	server := s3test.NewServer()
+	defer server.Close()
+
+	server.PutObject("strong", "exports/strong.csv", []byte("first"))
+	server.PutObject("strong", "exports/strong (1).csv", []byte("second"))
+	server.PutObject("strong", "exports/notes.txt", []byte("notes"))
+	server.PutObject("strong", "strong.csv", []byte("outside prefix"))
+
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := &s3.Source{Client: client, Bucket: "strong", Prefix: "exports/", Pattern: "strong*.csv"}
+	ctx := context.Background()
+
+	got, metadata, err := data.ReadAll(ctx, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "second", string(got), "the newest matching export should be opened")
+	assert.Equal(t, "exports/strong (1).csv", metadata.Name)
+	assert.Equal(t, "s3://strong/exports/strong (1).csv", metadata.Location)
+	assert.NotEmpty(t, metadata.Checksum)
+
+	got, _, err = data.ReadAll(ctx, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "second", string(got), "uncommitted exports should be opened again")
+
+	if err := source.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = data.ReadAll(ctx, source)
+	assert.True(t, errors.Is(err, data.ErrNotModified), "got %v", err)
+
+	server.PutObject("strong", "exports/strong (1).csv", []byte("edited"))
+
+	got, _, err = data.ReadAll(ctx, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "edited", string(got))
+
+	empty := &s3.Source{Client: client, Bucket: "strong", Prefix: "missing/"}
+
+	_, _, err = data.ReadAll(ctx, empty)
+	assert.Error(t, err)
+}
+
+// exportKeys leaves out the archive's hash markers.
+func exportKeys(keys []string) []string {
+	exports := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		if strings.HasSuffix(key, ".csv") {
+			exports = append(exports, key)
+		}
+	}
+
+	return exports
+}
+
+func TestArchiver_Archive(t *testing.T) {
+	t.Parallel()
+
+	server := s3test.NewServer()
+	defer server.Close()
+
+	server.AddBucket("archive")
+
+	client, err := server.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2022, time.November, 14, 7, 15, 24, 0, time.UTC)
+	archiver := &s3.Archiver{Client: client, Bucket: "archive", Prefix: "strong", Now: func() time.Time { return now }}
+
+	err = archiver.Archive(context.Background(), data.Metadata{Name: "exports/strong.csv"}, []byte("raw export"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"strong/20221114T071524Z-e8cee8678939-strong.csv"}, exportKeys(server.Keys("archive")))
+
+	got, _ := server.Object("archive", "strong/20221114T071524Z-e8cee8678939-strong.csv")
+	assert.Equal(t, "raw export", string(got))
+
+	// The same content fetched again later is not stored twice.
+	now = now.Add(time.Hour)
+
+	err = archiver.Archive(context.Background(), data.Metadata{Name: "exports/strong (1).csv"}, []byte("raw export"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = archiver.Archive(context.Background(), data.Metadata{Name: "exports/strong.csv"}, []byte("new export"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{
+		"strong/20221114T071524Z-e8cee8678939-strong.csv",
+		"strong/20221114T081524Z-c65257360874-strong.csv",
+	}, exportKeys(server.Keys("archive")))
+
+	missing := &s3.Archiver{Client: client, Bucket: "missing"}
+	assert.Error(t, missing.Archive(context.Background(), data.Metadata{Name: "strong.csv"}, nil))
+}
@@ -0,0 +1,292 @@
+// Package schedule runs a job repeatedly on a fixed interval or a cron
+// expression, never running two instances of the job at once.
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Schedule decides when a job runs next.
+type Schedule interface {
+	// Next returns the first run time strictly after t.
+	Next(t time.Time) time.Time
+}
+
+type interval time.Duration
+
+// Every runs a job every d, measured from the previous run's start.
+func Every(d time.Duration) Schedule {
+	return interval(d)
+}
+
+func (i interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+// Cron is a parsed five field cron expression: minute, hour, day of month,
+// month and day of week.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+
+	// Like cron(8), when both day fields are restricted a time matches if
+	// either does.
+	domAny, dowAny bool
+}
+
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseCron parses a five field cron expression. Each field accepts *, a
+// value, a range a-b, a list a,b and a step */n or a-b/n. Day of week 0 and 7
+// are both Sunday. Names such as JAN or MON are not supported.
+func ParseCron(expr string) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("error cron expression %q must have %d fields", expr, len(cronFields))
+	}
+
+	sets := make([]uint64, len(fields))
+
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing cron %s field %q: %w", cronFields[i].name, field, err)
+		}
+
+		sets[i] = set
+	}
+
+	// Sunday may be written as 0 or 7.
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return &Cron{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		low, high := min, max
+
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			lowPart, highPart, _ := strings.Cut(rangePart, "-")
+
+			var err error
+
+			if low, err = strconv.Atoi(lowPart); err != nil {
+				return 0, fmt.Errorf("invalid value %q", lowPart)
+			}
+
+			if high, err = strconv.Atoi(highPart); err != nil {
+				return 0, fmt.Errorf("invalid value %q", highPart)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", rangePart)
+			}
+
+			low = value
+			high = value
+
+			if hasStep {
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%s is outside %d-%d", part, min, max)
+		}
+
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+
+	return set, nil
+}
+
+// maxCronSearch bounds Next for expressions that never match, such as
+// "0 0 30 2 *".
+const maxCronSearch = 5 * 366 * 24 * time.Hour
+
+// Next returns the first minute after t matching the expression, in t's
+// location, or the zero time if none exists within five years.
+func (c *Cron) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxCronSearch)
+
+	for next.Before(limit) {
+		switch {
+		case !has(c.month, int(next.Month())):
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+		case !c.matchDay(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+		case !has(c.hour, next.Hour()):
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+		case !has(c.minute, next.Minute()):
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+
+	return time.Time{}
+}
+
+func (c *Cron) matchDay(t time.Time) bool {
+	dom := has(c.dom, t.Day())
+	dow := has(c.dow, int(t.Weekday()))
+
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+func has(set uint64, value int) bool {
+	return set&(1<<value) != 0
+}
+
+// Job is the work a Scheduler runs.
+type Job func(ctx context.Context) error
+
+// Scheduler runs a job on a schedule. A run that comes due while the previous
+// one is still going is skipped.
+type Scheduler struct {
+	log      *log.Logger
+	schedule Schedule
+	job      Job
+
+	running atomic.Bool
+	wg      sync.WaitGroup
+}
+
+// New returns a Scheduler running job on schedule.
+func New(log *log.Logger, schedule Schedule, job Job) *Scheduler {
+	return &Scheduler{
+		log:      log,
+		schedule: schedule,
+		job:      job,
+	}
+}
+
+// Run starts the job immediately and then whenever the schedule comes due,
+// until ctx is cancelled. It waits for an in-flight run before returning.
+func (s *Scheduler) Run(ctx context.Context) error {
+	defer s.wg.Wait()
+
+	s.Trigger(ctx)
+
+	for {
+		next := s.schedule.Next(time.Now())
+		if next.IsZero() {
+			return errors.New("error schedule has no next run")
+		}
+
+		s.log.Printf("next run at %s", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+			s.Trigger(ctx)
+		}
+	}
+}
+
+// Trigger starts a run in the background unless one is in progress. It
+// reports whether a run was started.
+func (s *Scheduler) Trigger(ctx context.Context) bool {
+	if !s.running.CompareAndSwap(false, true) {
+		s.log.Print("skipping run, previous run still in progress")
+		return false
+	}
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+		defer s.running.Store(false)
+
+		s.runJob(ctx)
+	}()
+
+	return true
+}
+
+// RunOnce runs the job in the foreground, logging its outcome.
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	if !s.running.CompareAndSwap(false, true) {
+		return errors.New("error run already in progress")
+	}
+
+	defer s.running.Store(false)
+
+	return s.runJob(ctx)
+}
+
+func (s *Scheduler) runJob(ctx context.Context) error {
+	start := time.Now()
+
+	s.log.Print("run started")
+
+	err := s.job(ctx)
+	if err != nil {
+		s.log.Printf("run failed after %s: %v", time.Since(start).Round(time.Millisecond), err)
+		return err
+	}
+
+	s.log.Printf("run succeeded in %s", time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package schedule_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/schedule"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	t.Parallel()
+
+	// Monday 14 November 2022.
+	from := time.Date(2022, time.November, 14, 7, 15, 24, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "every minute",
+			expr: "* * * * *",
+			want: time.Date(2022, time.November, 14, 7, 16, 0, 0, time.UTC),
+		},
+		{
+			name: "every 15 minutes",
+			expr: "*/15 * * * *",
+			want: time.Date(2022, time.November, 14, 7, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "daily at 6 rolls to tomorrow",
+			expr: "0 6 * * *",
+			want: time.Date(2022, time.November, 15, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "hour list",
+			expr: "30 6,20 * * *",
+			want: time.Date(2022, time.November, 14, 20, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "weekdays range",
+			expr: "0 8 * * 1-5",
+			want: time.Date(2022, time.November, 14, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "sunday as 7",
+			expr: "0 9 * * 7",
+			want: time.Date(2022, time.November, 20, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day of month or day of week",
+			expr: "0 0 1 * 3",
+			want: time.Date(2022, time.November, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "next year",
+			expr: "0 0 1 1 *",
+			want: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "never matches",
+			expr: "0 0 30 2 *",
+		},
+		{
+			name:    "too few fields",
+			expr:    "0 6 * *",
+			wantErr: true,
+		},
+		{
+			name:    "out of range",
+			expr:    "60 * * * *",
+			wantErr: true,
+		},
+		{
+			name:    "bad step",
+			expr:    "*/0 * * * *",
+			wantErr: true,
+		},
+		{
+			name:    "names unsupported",
+			expr:    "0 6 * * MON",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cron, err := schedule.ParseCron(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCron() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, tt.want, cron.Next(from))
+		})
+	}
+}
+
+func TestEvery(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2022, time.November, 14, 7, 15, 24, 0, time.UTC)
+
+	assert.Equal(t, from.Add(time.Hour), schedule.Every(time.Hour).Next(from))
+}
+
+func TestScheduler_Trigger(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	done := make(chan error, 2)
+
+	scheduler := schedule.New(log.New(io.Discard, "", 0), schedule.Every(time.Hour), func(ctx context.Context) error {
+		<-release
+		done <- nil
+		return nil
+	})
+
+	ctx := context.Background()
+
+	assert.True(t, scheduler.Trigger(ctx), "first run should start")
+	assert.False(t, scheduler.Trigger(ctx), "run should be skipped while the first is in progress")
+	assert.Error(t, scheduler.RunOnce(ctx), "foreground run should be refused while the first is in progress")
+
+	close(release)
+	<-done
+
+	assert.Eventually(t, func() bool {
+		return scheduler.Trigger(ctx)
+	}, time.Second, time.Millisecond, "run should start once the first has finished")
+
+	<-done
+}
+
+func TestScheduler_Run(t *testing.T) {
+	t.Parallel()
+
+	var runs atomic.Int32
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scheduler := schedule.New(log.New(io.Discard, "", 0), schedule.Every(10*time.Millisecond), func(ctx context.Context) error {
+		if runs.Add(1) == 3 {
+			cancel()
+		}
+
+		return errors.New("error failed runs are retried on schedule")
+	})
+
+	err := scheduler.Run(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.GreaterOrEqual(t, runs.Load(), int32(3))
+}
@@ -76,3 +76,16 @@ func (f *File) FileNotPresent() bool {
 	_, err := os.Stat(f.path)
 	return err != nil
 }
+
+// Remove deletes the stored token, e.g. after the user revokes access.
+func (f *File) Remove() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := os.Remove(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,96 @@
+package strava
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+const (
+	athletesPath = "athletes"
+	statsPath    = "stats"
+
+	measurementFeet = "feet"
+)
+
+// Athlete is the authenticated Strava athlete.
+type Athlete struct {
+	ID                    int64   `json:"id"`
+	Username              string  `json:"username"`
+	Firstname             string  `json:"firstname"`
+	Lastname              string  `json:"lastname"`
+	MeasurementPreference string  `json:"measurement_preference"`
+	Weight                float64 `json:"weight"`
+}
+
+// Name returns the athlete's full name.
+func (athlete Athlete) Name() string {
+	return strings.TrimSpace(athlete.Firstname + " " + athlete.Lastname)
+}
+
+// WeightUnit maps the measurement preference, "feet" or "meters", to the unit
+// used for Strong weights.
+func (athlete Athlete) WeightUnit() strong.WeightUnit {
+	if athlete.MeasurementPreference == measurementFeet {
+		return strong.Pounds
+	}
+
+	return strong.Kilograms
+}
+
+// Bodyweight returns the athlete's weight, recorded by Strava in kilograms,
+// converted to unit. It is 0 if the athlete has not set a weight.
+func (athlete Athlete) Bodyweight(unit strong.WeightUnit) float64 {
+	return unit.FromKilograms(athlete.Weight)
+}
+
+// ActivityTotal summarises a set of activities.
+type ActivityTotal struct {
+	Count         int     `json:"count"`
+	Distance      float64 `json:"distance"`
+	MovingTime    int     `json:"moving_time"`
+	ElapsedTime   int     `json:"elapsed_time"`
+	ElevationGain float64 `json:"elevation_gain"`
+}
+
+// AthleteStats are the athlete's activity totals. Strava only reports run,
+// ride and swim totals.
+type AthleteStats struct {
+	RecentRunTotals  ActivityTotal `json:"recent_run_totals"`
+	RecentRideTotals ActivityTotal `json:"recent_ride_totals"`
+	RecentSwimTotals ActivityTotal `json:"recent_swim_totals"`
+	YTDRunTotals     ActivityTotal `json:"ytd_run_totals"`
+	YTDRideTotals    ActivityTotal `json:"ytd_ride_totals"`
+	YTDSwimTotals    ActivityTotal `json:"ytd_swim_totals"`
+	AllRunTotals     ActivityTotal `json:"all_run_totals"`
+	AllRideTotals    ActivityTotal `json:"all_ride_totals"`
+	AllSwimTotals    ActivityTotal `json:"all_swim_totals"`
+}
+
+// GetAthlete returns the athlete the provider is authorized for.
+func (provider *Provider) GetAthlete(ctx context.Context) (Athlete, error) {
+	var athlete Athlete
+
+	err := provider.doJSON(ctx, http.MethodGet, fmt.Sprintf("%s/%s", provider.baseURL, athletePath), nil, http.StatusOK, &athlete)
+	if err != nil {
+		return Athlete{}, err
+	}
+
+	return athlete, nil
+}
+
+// GetAthleteStats returns the totals of the athlete with the given ID, which
+// must be the authorized athlete.
+func (provider *Provider) GetAthleteStats(ctx context.Context, id int64) (AthleteStats, error) {
+	var stats AthleteStats
+
+	err := provider.doJSON(ctx, http.MethodGet, fmt.Sprintf("%s/%s/%d/%s", provider.baseURL, athletesPath, id, statsPath), nil, http.StatusOK, &stats)
+	if err != nil {
+		return AthleteStats{}, err
+	}
+
+	return stats, nil
+}
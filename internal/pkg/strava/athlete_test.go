@@ -0,0 +1,92 @@
+package strava_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adiazny/strong/internal/pkg/strava/stravatest"
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvider_GetAthlete(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		athlete        stravatest.Athlete
+		wantName       string
+		wantUnit       strong.WeightUnit
+		wantBodyweight float64
+	}{
+		{
+			name:           "imperial athlete",
+			athlete:        stravatest.Athlete{ID: 7, Firstname: "Alan", Lastname: "Diaz", MeasurementPreference: "feet", Weight: 90},
+			wantName:       "Alan Diaz",
+			wantUnit:       strong.Pounds,
+			wantBodyweight: 198.4158,
+		},
+		{
+			name:           "metric athlete",
+			athlete:        stravatest.Athlete{ID: 8, Firstname: "Ana", MeasurementPreference: "meters", Weight: 62.5},
+			wantName:       "Ana",
+			wantUnit:       strong.Kilograms,
+			wantBodyweight: 62.5,
+		},
+		{
+			name:     "no weight",
+			athlete:  stravatest.Athlete{ID: 9, MeasurementPreference: "meters"},
+			wantUnit: strong.Kilograms,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := stravatest.NewServer()
+			defer server.Close()
+
+			server.SetAthlete(tt.athlete)
+
+			athlete, err := newTestProvider(server).GetAthlete(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			unit := athlete.WeightUnit()
+
+			assert.Equal(t, tt.athlete.ID, athlete.ID)
+			assert.Equal(t, tt.wantName, athlete.Name())
+			assert.Equal(t, tt.wantUnit, unit)
+			assert.InDelta(t, tt.wantBodyweight, athlete.Bodyweight(unit), 0.01)
+		})
+	}
+}
+
+func TestProvider_GetAthleteStats(t *testing.T) {
+	t.Parallel()
+
+	server := stravatest.NewServer()
+	defer server.Close()
+
+	server.SetAthlete(stravatest.Athlete{ID: 42})
+	server.AddActivity(stravatest.Activity{Name: "Run", SportType: "Run", StartDateLocal: "2022-11-14T07:15:24Z", ElapsedTime: 1800, Distance: 5000})
+	server.AddActivity(stravatest.Activity{Name: "Lift", SportType: "WeightTraining", StartDateLocal: "2022-11-15T07:15:24Z", ElapsedTime: 3600})
+
+	provider := newTestProvider(server)
+
+	stats, err := provider.GetAthleteStats(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 1, stats.AllRunTotals.Count)
+	assert.Equal(t, 5000.0, stats.AllRunTotals.Distance)
+	assert.Equal(t, 1800, stats.AllRunTotals.ElapsedTime)
+
+	_, err = provider.GetAthleteStats(context.Background(), 43)
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package strava
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+// WithDateRange limits syncs to workouts started at or after since and
+// before until. A zero time leaves that end open. Workouts outside the range
+// still count as history for PRs and are never pruned.
+func WithDateRange(since, until time.Time) Option {
+	return func(p *Provider) {
+		p.since = since
+		p.until = until
+	}
+}
+
+// WithMaxUploads caps the activities created per sync. The rest are left for
+// later syncs. Zero means no limit.
+func WithMaxUploads(max int) Option {
+	return func(p *Provider) {
+		p.maxUploads = max
+	}
+}
+
+// WithPacing waits d between writes to Strava.
+func WithPacing(d time.Duration) Option {
+	return func(p *Provider) {
+		p.pacing = d
+	}
+}
+
+// WithBackfill creates activities oldest first, visible only to the athlete
+// and hidden from followers' feeds, so history can be imported quietly.
+func WithBackfill(backfill bool) Option {
+	return func(p *Provider) {
+		p.backfill = backfill
+	}
+}
+
+// inRange returns the workouts within the configured date range. Workouts
+// with unparsable dates are only kept when no range is set.
+func (provider *Provider) inRange(workouts []strong.Workout) []strong.Workout {
+	if provider.since.IsZero() && provider.until.IsZero() {
+		return workouts
+	}
+
+	selected := make([]strong.Workout, 0, len(workouts))
+
+	for _, workout := range workouts {
+		start, err := workout.StartTime()
+		if err != nil {
+			provider.log.Printf("skipping workout with unparsable date %s", workout.Date)
+			continue
+		}
+
+		if !provider.since.IsZero() && start.Before(provider.since) {
+			continue
+		}
+
+		if !provider.until.IsZero() && !start.Before(provider.until) {
+			continue
+		}
+
+		selected = append(selected, workout)
+	}
+
+	return selected
+}
+
+// limitCreates orders creates oldest first when backfilling and keeps at
+// most maxUploads of them. It returns the kept actions and how many creates
+// were deferred.
+func (provider *Provider) limitCreates(planned []PlannedActivity) ([]PlannedActivity, int) {
+	if provider.backfill {
+		slots := make([]int, 0, len(planned))
+		creates := make([]PlannedActivity, 0, len(planned))
+
+		for i, p := range planned {
+			if p.Action == ActionCreate {
+				slots = append(slots, i)
+				creates = append(creates, p)
+			}
+		}
+
+		sort.SliceStable(creates, func(i, j int) bool {
+			return creates[i].workout.Date < creates[j].workout.Date
+		})
+
+		for i, slot := range slots {
+			planned[slot] = creates[i]
+		}
+	}
+
+	if provider.maxUploads <= 0 {
+		return planned, 0
+	}
+
+	kept := make([]PlannedActivity, 0, len(planned))
+	creates, deferred := 0, 0
+
+	for _, p := range planned {
+		if p.Action == ActionCreate {
+			if creates == provider.maxUploads {
+				deferred++
+				continue
+			}
+
+			creates++
+		}
+
+		kept = append(kept, p)
+	}
+
+	return kept, deferred
+}
+
+// private hides a backfilled activity from everyone but the athlete.
+func (provider *Provider) private(activity *Actvitiy) {
+	if !provider.backfill {
+		return
+	}
+
+	activity.Visibility = VisibilityOnlyMe
+	activity.HideFromHome = true
+}
+
+// pace waits between writes, returning early if ctx is done.
+func (provider *Provider) pace(ctx context.Context) error {
+	if provider.pacing <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(provider.pacing)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,101 @@
+package strava_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/strava"
+	"github.com/adiazny/strong/internal/pkg/strava/stravatest"
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvider_PlanBackfill(t *testing.T) {
+	t.Parallel()
+
+	workouts := []strong.Workout{
+		{Name: "Day D", Date: "2022-11-20T07:00:00Z", Duration: time.Hour},
+		{Name: "Day C", Date: "2022-11-18T07:00:00Z", Duration: time.Hour},
+		{Name: "Day B", Date: "2022-11-16T07:00:00Z", Duration: time.Hour},
+		{Name: "Day A", Date: "2022-11-14T07:00:00Z", Duration: time.Hour},
+	}
+
+	day := func(date string) time.Time {
+		t, _ := time.Parse(time.DateOnly, date)
+		return t
+	}
+
+	tests := []struct {
+		name         string
+		opts         []strava.Option
+		wantNames    []string
+		wantDeferred int
+		wantPrivate  bool
+	}{
+		{
+			name:      "everything",
+			wantNames: []string{"Day D", "Day C", "Day B", "Day A"},
+		},
+		{
+			name:      "since and until",
+			opts:      []strava.Option{strava.WithDateRange(day("2022-11-15"), day("2022-11-20"))},
+			wantNames: []string{"Day C", "Day B"},
+		},
+		{
+			name:         "max uploads",
+			opts:         []strava.Option{strava.WithMaxUploads(1)},
+			wantNames:    []string{"Day D"},
+			wantDeferred: 3,
+		},
+		{
+			name:         "backfill oldest first and private",
+			opts:         []strava.Option{strava.WithBackfill(true), strava.WithMaxUploads(2), strava.WithPacing(time.Millisecond)},
+			wantNames:    []string{"Day A", "Day B"},
+			wantDeferred: 2,
+			wantPrivate:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := stravatest.NewServer()
+			defer server.Close()
+
+			opts := append([]strava.Option{strava.WithBaseURL(server.URL)}, tt.opts...)
+			provider := strava.NewProvider(log.New(io.Discard, "", 0), server.Client(), opts...)
+
+			plan, err := provider.Plan(context.Background(), workouts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tt.wantDeferred, plan.Deferred)
+
+			if err := provider.Apply(context.Background(), plan); err != nil {
+				t.Fatal(err)
+			}
+
+			activities := server.Activities()
+
+			names := make([]string, 0, len(activities))
+			for _, activity := range activities {
+				names = append(names, activity.Name)
+
+				assert.Equal(t, tt.wantPrivate, activity.HideFromHome)
+
+				if tt.wantPrivate {
+					assert.Equal(t, strava.VisibilityOnlyMe, activity.Visibility)
+				}
+			}
+
+			assert.Equal(t, tt.wantNames, names)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package strava
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ActivityCache persists the last-seen list of Strava activities between runs.
+type ActivityCache struct {
+	path string
+	mu   sync.RWMutex
+}
+
+type cachedActivities struct {
+	After      time.Time  `json:"after"`
+	Activities []Actvitiy `json:"activities"`
+}
+
+func NewActivityCache(path string) (*ActivityCache, error) {
+	if path == "" {
+		return nil, errors.New("activity cache path is required")
+	}
+
+	return &ActivityCache{path: path}, nil
+}
+
+// Load returns the cached activities and the time they cover from. A zero
+// time means the cache holds the full activity history. Load returns an error
+// wrapping os.ErrNotExist when nothing has been cached yet.
+func (c *ActivityCache) Load() ([]Actvitiy, time.Time, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cached, err := c.load()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return cached.Activities, cached.After, nil
+}
+
+// Save replaces the cached activities with those covering the period
+// starting at after.
+func (c *ActivityCache) Save(after time.Time, activities []Actvitiy) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.save(cachedActivities{After: after, Activities: activities})
+}
+
+// Modify replaces the cached activities with the result of fn. It does
+// nothing if nothing has been cached yet.
+func (c *ActivityCache) Modify(fn func(activities []Actvitiy) []Actvitiy) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, err := c.load()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	cached.Activities = fn(cached.Activities)
+
+	return c.save(cached)
+}
+
+// Clear removes the cache file.
+func (c *ActivityCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := os.Remove(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (c *ActivityCache) load() (cachedActivities, error) {
+	var cached cachedActivities
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return cached, err
+	}
+
+	err = json.Unmarshal(data, &cached)
+
+	return cached, err
+}
+
+func (c *ActivityCache) save(cached cachedActivities) error {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.path, data, 0600)
+}
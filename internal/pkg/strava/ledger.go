@@ -0,0 +1,138 @@
+package strava
+
+import (
+	"strconv"
+
+	"github.com/adiazny/strong/internal/pkg/ledger"
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+// Destination names Strava in the sync ledger.
+const Destination = "strava"
+
+// Ledger records uploaded workouts so they are never created twice, even
+// when the activity list does not show them yet. *ledger.Ledger satisfies it.
+type Ledger interface {
+	Lookup(destination, workoutKey string) (ledger.Entry, bool)
+	RecordUpload(destination, workoutKey, contentHash, remoteID string) error
+	RecordError(destination, workoutKey, contentHash string, err error) error
+	Remove(destination, workoutKey string) error
+	Entries() []ledger.Entry
+}
+
+// WithLedger records every upload in l. Workouts it lists are not created
+// again and are updated when their content has changed.
+func WithLedger(l Ledger) Option {
+	return func(p *Provider) {
+		p.ledger = l
+	}
+}
+
+// ledgerEntry returns the workout's entry if the ledger lists it as uploaded.
+func (provider *Provider) ledgerEntry(workout strong.Workout) (ledger.Entry, bool) {
+	if provider.ledger == nil {
+		return ledger.Entry{}, false
+	}
+
+	entry, ok := provider.ledger.Lookup(Destination, workout.Key())
+
+	return entry, ok && entry.Uploaded()
+}
+
+// plannedDeletes lists uploaded workouts missing from workouts when pruning.
+func (provider *Provider) plannedDeletes(workouts []strong.Workout) []PlannedActivity {
+	if !provider.prune || provider.ledger == nil {
+		return nil
+	}
+
+	keys := make(map[string]struct{}, len(workouts))
+	for _, workout := range workouts {
+		keys[workout.Key()] = struct{}{}
+	}
+
+	deletes := make([]PlannedActivity, 0)
+
+	for _, entry := range provider.ledger.Entries() {
+		if entry.Destination != Destination || !entry.Uploaded() {
+			continue
+		}
+
+		if _, ok := keys[entry.WorkoutKey]; ok {
+			continue
+		}
+
+		id, err := strconv.ParseInt(entry.RemoteID, 10, 64)
+		if err != nil {
+			provider.log.Printf("error parsing ledger activity id %q: %v", entry.RemoteID, err)
+			continue
+		}
+
+		deletes = append(deletes, PlannedActivity{Action: ActionDelete, WorkoutKey: entry.WorkoutKey, ActivityID: id})
+	}
+
+	return deletes
+}
+
+func (provider *Provider) recordUpload(workout strong.Workout, activityID int64) {
+	if provider.ledger == nil {
+		return
+	}
+
+	err := provider.ledger.RecordUpload(Destination, workout.Key(), workout.ContentHash(), strconv.FormatInt(activityID, 10))
+	if err != nil {
+		provider.log.Printf("error recording upload of workout %s: %v", workout.Key(), err)
+	}
+}
+
+func (provider *Provider) recordError(workout strong.Workout, uploadErr error) {
+	if provider.ledger == nil {
+		return
+	}
+
+	if err := provider.ledger.RecordError(Destination, workout.Key(), workout.ContentHash(), uploadErr); err != nil {
+		provider.log.Printf("error recording failure of workout %s: %v", workout.Key(), err)
+	}
+}
+
+// recordPartialUpload records an activity created without all its options.
+// No content hash is stored, so the next sync updates the activity instead
+// of creating it again.
+func (provider *Provider) recordPartialUpload(workout strong.Workout, activityID int64, uploadErr error) {
+	if provider.ledger == nil {
+		return
+	}
+
+	err := provider.ledger.RecordUpload(Destination, workout.Key(), "", strconv.FormatInt(activityID, 10))
+	if err == nil {
+		err = provider.ledger.RecordError(Destination, workout.Key(), "", uploadErr)
+	}
+
+	if err != nil {
+		provider.log.Printf("error recording upload of workout %s: %v", workout.Key(), err)
+	}
+}
+
+// removeActivityRecord removes the ledger entries of the activity with id.
+func (provider *Provider) removeActivityRecord(id int64) {
+	if provider.ledger == nil {
+		return
+	}
+
+	remoteID := strconv.FormatInt(id, 10)
+
+	for _, entry := range provider.ledger.Entries() {
+		if entry.Destination == Destination && entry.RemoteID == remoteID {
+			provider.removeRecord(entry.WorkoutKey)
+		}
+	}
+}
+
+func (provider *Provider) removeRecord(workoutKey string) {
+	if provider.ledger == nil {
+		return
+	}
+
+	if err := provider.ledger.Remove(Destination, workoutKey); err != nil {
+		provider.log.Printf("error removing workout %s from the ledger: %v", workoutKey, err)
+	}
+}
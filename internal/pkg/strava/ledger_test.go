@@ -0,0 +1,247 @@
+package strava_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/ledger"
+	"github.com/adiazny/strong/internal/pkg/strava"
+	"github.com/adiazny/strong/internal/pkg/strava/stravatest"
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvider_UploadNewWorkoutsPartialCreate(t *testing.T) {
+	t.Parallel()
+
+	server := stravatest.NewServer()
+	defer server.Close()
+
+	l, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dayA := strong.Workout{Name: "Day A", Date: "2022-11-14T07:15:24Z", Duration: 30 * time.Minute}
+
+	provider := strava.NewProvider(log.New(io.Discard, "", 0), server.Client(), strava.WithBaseURL(server.URL),
+		strava.WithLedger(l), strava.WithBackfill(true))
+	ctx := context.Background()
+
+	// The activity is created but hiding it fails.
+	server.FailNext(http.MethodPut, "/activities/1", http.StatusInternalServerError, `{"message":"Error"}`)
+
+	err = provider.UploadNewWorkouts(ctx, []strong.Workout{dayA})
+	assert.Error(t, err)
+
+	entry, ok := l.Lookup(strava.Destination, dayA.Key())
+	assert.True(t, ok, "created activity should be recorded")
+	assert.Equal(t, "1", entry.RemoteID)
+	assert.NotEmpty(t, entry.LastError)
+
+	if err := provider.UploadNewWorkouts(ctx, []strong.Workout{dayA}); err != nil {
+		t.Fatal(err)
+	}
+
+	activities := server.Activities()
+	if assert.Len(t, activities, 1, "created activity should be updated, not posted again") {
+		assert.Equal(t, strava.VisibilityOnlyMe, activities[0].Visibility)
+		assert.True(t, activities[0].HideFromHome)
+	}
+
+	entry, _ = l.Lookup(strava.Destination, dayA.Key())
+	assert.Equal(t, dayA.ContentHash(), entry.ContentHash)
+	assert.Empty(t, entry.LastError)
+}
+
+func TestProvider_UpdateKeepsFlags(t *testing.T) {
+	t.Parallel()
+
+	server := stravatest.NewServer()
+	defer server.Close()
+
+	l, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dayA := strong.Workout{Name: "Day A", Date: "2022-11-14T07:15:24Z", Duration: 30 * time.Minute}
+
+	// Backfilled and marked as a commute on Strava.
+	existing := server.AddActivity(stravatest.Activity{
+		Name: "Day A", SportType: "WeightTraining", StartDateLocal: dayA.Date, ElapsedTime: 1800,
+		HideFromHome: true, Commute: true, Visibility: strava.VisibilityOnlyMe,
+	})
+
+	if err := l.RecordUpload(strava.Destination, dayA.Key(), "edited since", strconv.FormatInt(existing.ID, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := strava.NewProvider(log.New(io.Discard, "", 0), server.Client(), strava.WithBaseURL(server.URL), strava.WithLedger(l))
+
+	if err := provider.UploadNewWorkouts(context.Background(), []strong.Workout{dayA}); err != nil {
+		t.Fatal(err)
+	}
+
+	activities := server.Activities()
+	if assert.Len(t, activities, 1) {
+		assert.True(t, activities[0].HideFromHome, "update should not unhide the activity")
+		assert.True(t, activities[0].Commute, "update should not clear commute")
+		assert.Equal(t, strava.VisibilityOnlyMe, activities[0].Visibility)
+	}
+}
+
+func TestProvider_UpdateAppliesRules(t *testing.T) {
+	t.Parallel()
+
+	server := stravatest.NewServer()
+	defer server.Close()
+
+	l, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+
+	err = os.WriteFile(path, []byte(`[
+		{"program": "", "trainer": true},
+		{"program": "Deload", "hide_from_home": false}
+	]`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := strava.LoadActivityRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deload := strong.Workout{Name: "Deload", Date: "2022-11-14T07:15:24Z", Duration: 30 * time.Minute}
+
+	existing := server.AddActivity(stravatest.Activity{
+		Name: "Deload", SportType: "WeightTraining", StartDateLocal: deload.Date, ElapsedTime: 1800, HideFromHome: true,
+	})
+
+	if err := l.RecordUpload(strava.Destination, deload.Key(), "edited since", strconv.FormatInt(existing.ID, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := strava.NewProvider(log.New(io.Discard, "", 0), server.Client(), strava.WithBaseURL(server.URL),
+		strava.WithLedger(l), strava.WithActivityRules(rules))
+
+	if err := provider.UploadNewWorkouts(context.Background(), []strong.Workout{deload}); err != nil {
+		t.Fatal(err)
+	}
+
+	activities := server.Activities()
+	if assert.Len(t, activities, 1) {
+		assert.True(t, activities[0].Trainer, "rule set trainer should be applied on update")
+		assert.False(t, activities[0].HideFromHome, "rule cleared hide_from_home should be applied on update")
+	}
+}
+
+func TestProvider_DeletedActivity(t *testing.T) {
+	t.Parallel()
+
+	server := stravatest.NewServer()
+	defer server.Close()
+
+	l, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dayA := strong.Workout{Name: "Day A", Date: "2022-11-14T07:15:24Z", Duration: 30 * time.Minute}
+	dayB := strong.Workout{Name: "Day B", Date: "2022-11-16T06:54:38Z", Duration: 45 * time.Minute}
+
+	// Both were uploaded, but the activities are gone from Strava.
+	if err := l.RecordUpload(strava.Destination, dayA.Key(), dayA.ContentHash(), "98"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.RecordUpload(strava.Destination, dayB.Key(), "edited since", "99"); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := strava.NewProvider(log.New(io.Discard, "", 0), server.Client(), strava.WithBaseURL(server.URL), strava.WithLedger(l))
+	ctx := context.Background()
+
+	err = provider.HandleWebhookEvent(ctx, strava.WebhookEvent{ObjectType: "activity", AspectType: "delete", ObjectID: 98})
+	assert.NoError(t, err)
+
+	_, ok := l.Lookup(strava.Destination, dayA.Key())
+	assert.False(t, ok, "delete event should drop the ledger entry")
+
+	// Updating Day B finds its activity deleted; the sync carries on.
+	if err := provider.UploadNewWorkouts(ctx, []strong.Workout{dayA, dayB}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, ok = l.Lookup(strava.Destination, dayB.Key())
+	assert.False(t, ok, "missing activity should be dropped from the ledger")
+
+	if err := provider.UploadNewWorkouts(ctx, []strong.Workout{dayA, dayB}); err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, 2)
+	for _, activity := range server.Activities() {
+		names = append(names, activity.Name)
+	}
+
+	assert.ElementsMatch(t, []string{"Day A", "Day B"}, names, "deleted workouts should be created again")
+}
+
+func TestProvider_UploadNewWorkoutsWithLedger(t *testing.T) {
+	t.Parallel()
+
+	server := stravatest.NewServer()
+	defer server.Close()
+
+	l, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dayA := strong.Workout{Name: "Day A", Date: "2022-11-14T07:15:24Z", Duration: 30 * time.Minute}
+	dayB := strong.Workout{Name: "Day B", Date: "2022-11-16T06:54:38Z", Duration: 45 * time.Minute}
+
+	// Day A was uploaded by an earlier run but is not listed by Strava yet.
+	if err := l.RecordUpload(strava.Destination, dayA.Key(), dayA.ContentHash(), "99"); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := strava.NewProvider(log.New(io.Discard, "", 0), server.Client(), strava.WithBaseURL(server.URL), strava.WithLedger(l))
+	ctx := context.Background()
+
+	server.FailNext(http.MethodPost, "/activities", http.StatusInternalServerError, `{"message":"Error"}`)
+
+	err = provider.UploadNewWorkouts(ctx, []strong.Workout{dayA, dayB})
+	assert.Error(t, err)
+
+	entry, ok := l.Lookup(strava.Destination, dayB.Key())
+	assert.True(t, ok, "failed upload should be recorded")
+	assert.False(t, entry.Uploaded())
+	assert.NotEmpty(t, entry.LastError)
+
+	if err := provider.UploadNewWorkouts(ctx, []strong.Workout{dayA, dayB}); err != nil {
+		t.Fatal(err)
+	}
+
+	activities := server.Activities()
+	assert.Len(t, activities, 1, "ledger should stop Day A being posted again")
+	assert.Equal(t, "Day B", activities[0].Name)
+
+	entry, _ = l.Lookup(strava.Destination, dayB.Key())
+	assert.Equal(t, "1", entry.RemoteID)
+	assert.Equal(t, dayB.ContentHash(), entry.ContentHash)
+	assert.Empty(t, entry.LastError)
+}
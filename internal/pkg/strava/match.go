@@ -0,0 +1,257 @@
+package strava
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+const (
+	defaultMatchTolerance    = 10 * time.Minute
+	defaultDurationTolerance = 0.25
+
+	// maxTimezoneOffset bounds the whole-quarter-hour shifts considered to be
+	// timezone differences rather than different sessions.
+	maxTimezoneOffset = 14 * time.Hour
+	timezoneStep      = 15 * time.Minute
+	// timezoneSlack is how far a shifted start may be from a whole quarter
+	// hour. It is kept apart from Tolerance, which at 7.5 minutes or more
+	// would let every start within maxTimezoneOffset count as shifted.
+	timezoneSlack = 2 * time.Minute
+
+	markerPrefix = "strong:"
+)
+
+// Matcher decides whether a Strong workout already exists as a Strava
+// activity.
+type Matcher struct {
+	// Tolerance is how far apart start and end times may be for a workout and
+	// an activity to be considered overlapping.
+	Tolerance time.Duration
+	// DurationTolerance is the allowed relative difference between the
+	// workout duration and the activity elapsed time, e.g. 0.25 for 25%.
+	DurationTolerance float64
+	// MatchTimezoneShifts treats activities starting a whole number of quarter
+	// hours away as the same session recorded in another timezone.
+	MatchTimezoneShifts bool
+}
+
+// DefaultMatcher returns the Matcher used when none is configured.
+func DefaultMatcher() Matcher {
+	return Matcher{
+		Tolerance:           defaultMatchTolerance,
+		DurationTolerance:   defaultDurationTolerance,
+		MatchTimezoneShifts: true,
+	}
+}
+
+// MatchResult explains why a workout was matched to an activity or treated
+// as new.
+type MatchResult struct {
+	Workout  strong.Workout
+	Activity *Actvitiy
+	Reason   string
+}
+
+func (result MatchResult) Matched() bool {
+	return result.Activity != nil
+}
+
+func (result MatchResult) String() string {
+	if result.Matched() {
+		return fmt.Sprintf("workout %q at %s matched activity %d %q: %s", result.Workout.Name, result.Workout.Date, result.Activity.ID, result.Activity.Name, result.Reason)
+	}
+
+	return fmt.Sprintf("workout %q at %s is new: %s", result.Workout.Name, result.Workout.Date, result.Reason)
+}
+
+// MatchReport holds one MatchResult per workout, in workout order.
+type MatchReport []MatchResult
+
+// New returns the workouts that did not match any activity.
+func (report MatchReport) New() []strong.Workout {
+	workouts := make([]strong.Workout, 0)
+
+	for _, result := range report {
+		if !result.Matched() {
+			workouts = append(workouts, result.Workout)
+		}
+	}
+
+	return workouts
+}
+
+// Marker returns the tag embedded in uploaded activity descriptions that
+// identifies the source workout.
+func Marker(workout strong.Workout) string {
+	return markerPrefix + workout.Key()
+}
+
+// Match pairs each workout with at most one activity. An activity is never
+// matched to more than one workout. Description markers take precedence over
+// time-based matching.
+func (m Matcher) Match(activities []Actvitiy, workouts []strong.Workout) MatchReport {
+	report := make(MatchReport, len(workouts))
+	used := make(map[int]bool)
+
+	// Markers are exact, so resolve them before fuzzy matching can claim
+	// the activity for a neighbouring workout.
+	for i, workout := range workouts {
+		report[i] = MatchResult{Workout: workout}
+
+		marker := Marker(workout)
+
+		for j := range activities {
+			if !used[j] && strings.Contains(activities[j].Description, marker) {
+				used[j] = true
+				report[i].Activity = &activities[j]
+				report[i].Reason = fmt.Sprintf("description contains marker %q", marker)
+
+				break
+			}
+		}
+	}
+
+	// Overlapping sessions are matched before timezone shifts so a second
+	// session on the same day cannot claim the first session's activity.
+	m.matchPass(report, activities, used, false)
+
+	if m.MatchTimezoneShifts {
+		m.matchPass(report, activities, used, true)
+	}
+
+	return report
+}
+
+func (m Matcher) matchPass(report MatchReport, activities []Actvitiy, used map[int]bool, shifted bool) {
+	for i := range report {
+		if report[i].Matched() {
+			continue
+		}
+
+		workout := report[i].Workout
+
+		start, err := workout.StartTime()
+		if err != nil {
+			report[i].Reason = fmt.Sprintf("unparsable workout date: %v", err)
+			continue
+		}
+
+		if !shifted {
+			report[i].Reason = "no activity near workout start"
+		}
+
+		bestDelta := time.Duration(-1)
+
+		for j := range activities {
+			if used[j] {
+				continue
+			}
+
+			reason, delta, ok := m.compare(start, workout.Duration, activities[j], shifted)
+			if ok {
+				used[j] = true
+				report[i].Activity = &activities[j]
+				report[i].Reason = reason
+
+				break
+			}
+
+			// Keep the rejection closest in time as the explanation.
+			if reason != "" && (bestDelta < 0 || delta < bestDelta) {
+				bestDelta = delta
+				report[i].Reason = fmt.Sprintf("closest activity %d %q rejected: %s", activities[j].ID, activities[j].Name, reason)
+			}
+		}
+	}
+}
+
+// compare reports whether activity is the same session as a workout starting
+// at start, either overlapping it or, when shifted is set, offset from it by a
+// timezone difference. When it is not, reason explains the rejection of a
+// nearby activity and is empty for activities too far away to be relevant.
+func (m Matcher) compare(start time.Time, duration time.Duration, activity Actvitiy, shifted bool) (reason string, delta time.Duration, ok bool) {
+	activityStart, err := time.Parse(time.RFC3339, activity.StartDateLocal)
+	if err != nil {
+		return "", 0, false
+	}
+
+	activityDuration := time.Duration(activity.ElapsedTime) * time.Second
+
+	delta = absDuration(activityStart.Sub(start))
+
+	offset := time.Duration(0)
+
+	switch {
+	case !shifted && !m.overlaps(start, duration, activityStart, activityDuration):
+		return "", delta, false
+	case shifted:
+		offset = m.timezoneOffset(activityStart.Sub(start))
+		if offset == 0 {
+			return "", delta, false
+		}
+	}
+
+	if activity.SportType != weightTraining {
+		return fmt.Sprintf("sport type %s is not %s", activity.SportType, weightTraining), delta, false
+	}
+
+	if !m.similarDuration(duration, activityDuration) {
+		return fmt.Sprintf("elapsed time %s differs from workout duration %s", activityDuration, duration), delta, false
+	}
+
+	if offset != 0 {
+		return fmt.Sprintf("start times differ by a timezone offset of %s", offset), delta, true
+	}
+
+	return fmt.Sprintf("overlaps workout, starting %s apart", delta), delta, true
+}
+
+func (m Matcher) overlaps(start time.Time, duration time.Duration, activityStart time.Time, activityDuration time.Duration) bool {
+	if absDuration(activityStart.Sub(start)) <= m.Tolerance {
+		return true
+	}
+
+	end := start.Add(duration)
+	activityEnd := activityStart.Add(activityDuration)
+
+	return !activityStart.After(end.Add(m.Tolerance)) && !start.After(activityEnd.Add(m.Tolerance))
+}
+
+// timezoneOffset returns the non-zero whole quarter-hour shift within
+// timezoneSlack of delta, or zero if there is none.
+func (m Matcher) timezoneOffset(delta time.Duration) time.Duration {
+	if absDuration(delta) > maxTimezoneOffset+timezoneSlack {
+		return 0
+	}
+
+	offset := delta.Round(timezoneStep)
+	if absDuration(delta-offset) > timezoneSlack {
+		return 0
+	}
+
+	return offset
+}
+
+func (m Matcher) similarDuration(a, b time.Duration) bool {
+	if a == 0 || b == 0 {
+		return true
+	}
+
+	longest := a
+	if b > a {
+		longest = b
+	}
+
+	return float64(absDuration(a-b)) <= m.DurationTolerance*float64(longest)
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+
+	return d
+}
@@ -0,0 +1,136 @@
+package strava_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/strava"
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	t.Parallel()
+
+	dayA := strong.Workout{Name: "Day A", Date: "2022-11-14T07:15:24Z", Duration: 30 * time.Minute}
+	dayB := strong.Workout{Name: "Day B", Date: "2022-11-14T15:30:00Z", Duration: 30 * time.Minute}
+
+	tests := []struct {
+		name        string
+		activities  []strava.Actvitiy
+		workouts    []strong.Workout
+		wantMatched []bool
+		wantReason  []string
+	}{
+		{
+			name:        "no activities",
+			workouts:    []strong.Workout{dayA},
+			wantMatched: []bool{false},
+			wantReason:  []string{"no activity near workout start"},
+		},
+		{
+			name: "exact start",
+			activities: []strava.Actvitiy{
+				{ID: 1, Name: "Day A", SportType: "WeightTraining", StartDateLocal: "2022-11-14T07:15:24Z", ElapsedTime: 1800},
+			},
+			workouts:    []strong.Workout{dayA},
+			wantMatched: []bool{true},
+			wantReason:  []string{"overlaps workout"},
+		},
+		{
+			name: "manual entry a minute off",
+			activities: []strava.Actvitiy{
+				{ID: 1, Name: "Lift", SportType: "WeightTraining", StartDateLocal: "2022-11-14T07:16:00Z", ElapsedTime: 1740},
+			},
+			workouts:    []strong.Workout{dayA},
+			wantMatched: []bool{true},
+			wantReason:  []string{"overlaps workout"},
+		},
+		{
+			name: "timezone difference",
+			activities: []strava.Actvitiy{
+				{ID: 1, Name: "Day A", SportType: "WeightTraining", StartDateLocal: "2022-11-14T08:15:24Z", ElapsedTime: 1800},
+			},
+			workouts:    []strong.Workout{dayA},
+			wantMatched: []bool{true},
+			wantReason:  []string{"timezone offset of 1h0m0s"},
+		},
+		{
+			name: "session hours away off the quarter hour stays new",
+			activities: []strava.Actvitiy{
+				{ID: 1, Name: "Morning Lift", SportType: "WeightTraining", StartDateLocal: "2022-11-15T06:00:00Z", ElapsedTime: 1800},
+			},
+			workouts:    []strong.Workout{{Name: "Evening", Date: "2022-11-14T19:07:00Z", Duration: 30 * time.Minute}},
+			wantMatched: []bool{false},
+			wantReason:  []string{"no activity near workout start"},
+		},
+		{
+			name: "different sport type",
+			activities: []strava.Actvitiy{
+				{ID: 1, Name: "Run", SportType: "Run", StartDateLocal: "2022-11-14T07:15:24Z", ElapsedTime: 1800},
+			},
+			workouts:    []strong.Workout{dayA},
+			wantMatched: []bool{false},
+			wantReason:  []string{"sport type Run"},
+		},
+		{
+			name: "duration too different",
+			activities: []strava.Actvitiy{
+				{ID: 1, Name: "Day A", SportType: "WeightTraining", StartDateLocal: "2022-11-14T07:15:24Z", ElapsedTime: 7200},
+			},
+			workouts:    []strong.Workout{dayA},
+			wantMatched: []bool{false},
+			wantReason:  []string{"elapsed time 2h0m0s differs"},
+		},
+		{
+			name: "description marker",
+			activities: []strava.Actvitiy{
+				{ID: 1, Name: "Renamed", SportType: "WeightTraining", StartDateLocal: "2022-11-15T19:00:00Z", ElapsedTime: 60, Description: "PR day\nstrong:20221114T071524"},
+			},
+			workouts:    []strong.Workout{dayA},
+			wantMatched: []bool{true},
+			wantReason:  []string{"marker"},
+		},
+		{
+			name: "second session same day stays new",
+			activities: []strava.Actvitiy{
+				{ID: 1, Name: "Day A", SportType: "WeightTraining", StartDateLocal: "2022-11-14T07:15:24Z", ElapsedTime: 1800},
+			},
+			workouts:    []strong.Workout{dayB, dayA},
+			wantMatched: []bool{false, true},
+			wantReason:  []string{"no activity near workout start", "overlaps workout"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			report := strava.DefaultMatcher().Match(tt.activities, tt.workouts)
+
+			assert.Len(t, report, len(tt.workouts))
+
+			for i, result := range report {
+				assert.Equal(t, tt.wantMatched[i], result.Matched(), result.String())
+				assert.Contains(t, result.Reason, tt.wantReason[i])
+			}
+		})
+	}
+}
+
+func TestMatcher_MatchTolerance(t *testing.T) {
+	t.Parallel()
+
+	workouts := []strong.Workout{{Name: "Day A", Date: "2022-11-14T07:15:24Z", Duration: 30 * time.Minute}}
+	activities := []strava.Actvitiy{
+		{ID: 1, Name: "Day A", SportType: "WeightTraining", StartDateLocal: "2022-11-14T07:55:00Z", ElapsedTime: 1800},
+	}
+
+	strict := strava.Matcher{Tolerance: time.Minute, DurationTolerance: 0.1}
+	assert.Len(t, strict.Match(activities, workouts).New(), 1)
+
+	loose := strava.Matcher{Tolerance: 15 * time.Minute, DurationTolerance: 0.1}
+	assert.Empty(t, loose.Match(activities, workouts).New())
+}
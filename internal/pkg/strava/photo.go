@@ -0,0 +1,95 @@
+package strava
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+const photosPath = "photos"
+
+// PhotoRenderer draws an image, such as a summary card, for a workout. The
+// history is all known workouts.
+type PhotoRenderer func(workout strong.Workout, history []strong.Workout) ([]byte, error)
+
+// WithPhotos attaches the PNG drawn by render to every activity created.
+func WithPhotos(render PhotoRenderer) Option {
+	return func(p *Provider) {
+		p.renderPhoto = render
+	}
+}
+
+// AttachPhoto uploads a PNG to an activity. Strava's public API documents no
+// photo upload endpoint; this uses the multipart activities/{id}/photos form
+// its own clients post to, which Strava may reject for third party apps.
+func (provider *Provider) AttachPhoto(ctx context.Context, activityID int64, name string, data []byte) error {
+	var body bytes.Buffer
+
+	writer := multipart.NewWriter(&body)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, name))
+	header.Set("Content-Type", "image/png")
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("error creating photo part: %w", err)
+	}
+
+	if _, err := part.Write(data); err != nil {
+		return fmt.Errorf("error writing photo part: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("error closing photo body: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/%d/%s", provider.baseURL, activitiesPath, activityID, photosPath)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return fmt.Errorf("error creating http post request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := provider.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error performing http post request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		provider.log.Printf("%s\n", respBody)
+
+		return fmt.Errorf("error response status code is %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// attachPhoto renders and attaches the workout photo, if configured. The
+// activity already exists, so failures are logged rather than returned.
+func (provider *Provider) attachPhoto(ctx context.Context, activityID int64, workout strong.Workout, history []strong.Workout) {
+	if provider.renderPhoto == nil || activityID == 0 {
+		return
+	}
+
+	data, err := provider.renderPhoto(workout, history)
+	if err != nil {
+		provider.log.Printf("error rendering photo for workout %s: %v", workout.Date, err)
+		return
+	}
+
+	if err := provider.AttachPhoto(ctx, activityID, workout.Key()+".png", data); err != nil {
+		provider.log.Printf("error attaching photo to activity %d: %v", activityID, err)
+	}
+}
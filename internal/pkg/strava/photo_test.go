@@ -0,0 +1,83 @@
+package strava_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/strava"
+	"github.com/adiazny/strong/internal/pkg/strava/stravatest"
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvider_UploadNewWorkoutsWithPhotos(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		render     strava.PhotoRenderer
+		fail       bool
+		wantPhotos int
+	}{
+		{
+			name: "photo attached",
+			render: func(workout strong.Workout, history []strong.Workout) ([]byte, error) {
+				return []byte("png " + workout.Name), nil
+			},
+			wantPhotos: 1,
+		},
+		{
+			name: "render error does not fail upload",
+			render: func(workout strong.Workout, history []strong.Workout) ([]byte, error) {
+				return nil, errors.New("no font")
+			},
+		},
+		{
+			name: "rejected photo does not fail upload",
+			render: func(workout strong.Workout, history []strong.Workout) ([]byte, error) {
+				return []byte("png"), nil
+			},
+			fail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := stravatest.NewServer()
+			defer server.Close()
+
+			if tt.fail {
+				server.FailNext(http.MethodPost, "/activities/1/photos", http.StatusForbidden, `{"message":"Forbidden"}`)
+			}
+
+			provider := strava.NewProvider(log.New(io.Discard, "", 0), server.Client(), strava.WithBaseURL(server.URL), strava.WithPhotos(tt.render))
+
+			workouts := []strong.Workout{{Name: "Day A", Date: "2022-11-14T07:15:24Z", Duration: 30 * time.Minute}}
+
+			if err := provider.UploadNewWorkouts(context.Background(), workouts); err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Len(t, server.Activities(), 1)
+
+			photos := server.Photos()
+			assert.Len(t, photos, tt.wantPhotos)
+
+			if tt.wantPhotos > 0 {
+				assert.Equal(t, int64(1), photos[0].ActivityID)
+				assert.Equal(t, "20221114T071524.png", photos[0].Name)
+				assert.Equal(t, "image/png", photos[0].ContentType)
+				assert.Equal(t, []byte("png Day A"), photos[0].Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+// Action is what a sync does to one Strava activity.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// PlannedActivity is one write a sync would make. Activity holds the rendered
+// activity for creates and updates.
+type PlannedActivity struct {
+	Action     Action   `json:"action"`
+	WorkoutKey string   `json:"workout_key"`
+	ActivityID int64    `json:"activity_id,omitempty"`
+	Activity   Actvitiy `json:"activity"`
+
+	workout strong.Workout
+	// partial is set for an update finishing a create whose update only
+	// options could not be applied.
+	partial bool
+}
+
+// Plan lists the writes a sync would make, in the order Apply makes them.
+type Plan struct {
+	Activities []PlannedActivity `json:"activities"`
+	Unchanged  int               `json:"unchanged"`
+	Deferred   int               `json:"deferred"`
+
+	history []strong.Workout
+}
+
+// Empty reports whether the plan makes no changes.
+func (plan Plan) Empty() bool {
+	return len(plan.Activities) == 0
+}
+
+// String lists the planned writes with their rendered names and descriptions.
+func (plan Plan) String() string {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "%d activities to change, %d already on strava, %d deferred\n", len(plan.Activities), plan.Unchanged, plan.Deferred)
+
+	for _, planned := range plan.Activities {
+		fmt.Fprintf(&builder, "\n%s %s", planned.Action, planned.WorkoutKey)
+
+		if planned.ActivityID != 0 {
+			fmt.Fprintf(&builder, " activity %d", planned.ActivityID)
+		}
+
+		builder.WriteString("\n")
+
+		if planned.Action != ActionDelete {
+			fmt.Fprintf(&builder, "%s\n%s\n", planned.Activity.Name, strings.TrimSpace(planned.Activity.Description))
+		}
+	}
+
+	return builder.String()
+}
+
+// WithPrune plans deleting activities whose workouts the ledger recorded as
+// uploaded but which are no longer in the Strong export. It needs a ledger.
+func WithPrune(prune bool) Option {
+	return func(p *Provider) {
+		p.prune = prune
+	}
+}
+
+// Plan works out the activities a sync of workouts would create, update and
+// delete, rendering their names and descriptions. It only reads from Strava.
+func (provider *Provider) Plan(ctx context.Context, workouts []strong.Workout) (Plan, error) {
+	candidates := provider.inRange(workouts)
+
+	var stravaActivities []Actvitiy
+
+	if len(candidates) > 0 {
+		var err error
+
+		stravaActivities, err = provider.GetActivities(ctx, oldestWorkoutTime(candidates))
+		if err != nil {
+			return Plan{}, err
+		}
+	}
+
+	report := provider.matcher.Match(stravaActivities, candidates)
+
+	for _, result := range report {
+		provider.log.Print(result)
+	}
+
+	plan := Plan{history: workouts}
+	planned := make([]PlannedActivity, 0)
+
+	for _, result := range report {
+		workout := result.Workout
+
+		entry, recorded := provider.ledgerEntry(workout)
+
+		switch {
+		case recorded && entry.ContentHash != workout.ContentHash():
+			id, err := strconv.ParseInt(entry.RemoteID, 10, 64)
+			if err != nil {
+				return Plan{}, fmt.Errorf("error parsing ledger activity id %q: %w", entry.RemoteID, err)
+			}
+
+			planned = append(planned, PlannedActivity{
+				Action:     ActionUpdate,
+				WorkoutKey: workout.Key(),
+				ActivityID: id,
+				workout:    workout,
+				partial:    entry.ContentHash == "",
+			})
+		case recorded:
+			provider.log.Printf("workout %s already uploaded as activity %s", workout.Key(), entry.RemoteID)
+			plan.Unchanged++
+		case result.Matched():
+			plan.Unchanged++
+		default:
+			planned = append(planned, PlannedActivity{Action: ActionCreate, WorkoutKey: workout.Key(), workout: workout})
+		}
+	}
+
+	planned, plan.Deferred = provider.limitCreates(planned)
+
+	rendered := make([]strong.Workout, 0, len(planned))
+	for _, p := range planned {
+		rendered = append(rendered, p.workout)
+	}
+
+	activities, err := provider.convertToStrava(rendered, workouts)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	for i := range planned {
+		planned[i].Activity = activities[i]
+		planned[i].Activity.ID = planned[i].ActivityID
+
+		if planned[i].Action == ActionCreate || planned[i].partial {
+			provider.private(&planned[i].Activity)
+		}
+	}
+
+	plan.Activities = append(planned, provider.plannedDeletes(workouts)...)
+
+	return plan, nil
+}
+
+// Apply makes the writes of plan, recording each in the ledger and pacing
+// them if configured. It stops at the first failed create or update; failed
+// deletes are logged and skipped.
+func (provider *Provider) Apply(ctx context.Context, plan Plan) error {
+	for i, planned := range plan.Activities {
+		activity := planned.Activity
+
+		if i > 0 {
+			if err := provider.pace(ctx); err != nil {
+				return err
+			}
+		}
+
+		switch planned.Action {
+		case ActionCreate:
+			created, err := provider.createActivity(ctx, planned.workout, activity)
+			if err != nil && created.ID != 0 {
+				provider.recordPartialUpload(planned.workout, created.ID, err)
+
+				return fmt.Errorf("%v activity: %s and date %s", err, activity.Name, activity.StartDateLocal)
+			}
+
+			if err != nil {
+				provider.recordError(planned.workout, err)
+
+				return fmt.Errorf("%v activity: %s and date %s", err, activity.Name, activity.StartDateLocal)
+			}
+
+			provider.recordUpload(planned.workout, created.ID)
+			provider.attachPhoto(ctx, created.ID, planned.workout, plan.history)
+		case ActionUpdate:
+			// Rules may clear flags as well as set them, except when finishing
+			// a create, where backfill privacy wins as it does on create.
+			flags := setFlags(activity)
+			if !planned.partial {
+				flags = flags.override(provider.rules.flags(planned.workout))
+			}
+
+			_, err := provider.updateActivity(ctx, activity, flags)
+			if errors.Is(err, ErrNotFound) {
+				// The activity was deleted on Strava; without the entry the
+				// workout is created again on the next sync.
+				provider.log.Printf("activity %d of workout %s no longer exists, dropping it from the ledger", planned.ActivityID, planned.WorkoutKey)
+				provider.removeRecord(planned.WorkoutKey)
+
+				break
+			}
+
+			if err != nil {
+				provider.recordError(planned.workout, err)
+
+				return fmt.Errorf("error updating activity %d: %w", planned.ActivityID, err)
+			}
+
+			provider.recordUpload(planned.workout, planned.ActivityID)
+		case ActionDelete:
+			// Strava's API does not document deletion and usually refuses it,
+			// so a failure keeps the entry for the next sync rather than
+			// failing this one.
+			if err := provider.DeleteActivity(ctx, planned.ActivityID); err != nil {
+				provider.log.Printf("error deleting activity %d of workout %s, skipping it: %v", planned.ActivityID, planned.WorkoutKey, err)
+				continue
+			}
+
+			provider.removeRecord(planned.WorkoutKey)
+		}
+	}
+
+	return nil
+}
+
+// DeleteActivity deletes the activity with the given ID. Strava's public API
+// does not document activity deletion, so it may be refused.
+func (provider *Provider) DeleteActivity(ctx context.Context, id int64) error {
+	url := fmt.Sprintf("%s/%s/%d", provider.baseURL, activitiesPath, id)
+
+	if err := provider.doJSON(ctx, http.MethodDelete, url, nil, http.StatusNoContent, nil); err != nil {
+		return err
+	}
+
+	if provider.cache == nil {
+		return nil
+	}
+
+	return provider.cache.Modify(func(activities []Actvitiy) []Actvitiy {
+		return filterActivities(activities, func(activity Actvitiy) bool {
+			return activity.ID != id
+		})
+	})
+}
@@ -0,0 +1,162 @@
+package strava_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/ledger"
+	"github.com/adiazny/strong/internal/pkg/strava"
+	"github.com/adiazny/strong/internal/pkg/strava/stravatest"
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvider_Plan(t *testing.T) {
+	t.Parallel()
+
+	server := stravatest.NewServer()
+	defer server.Close()
+
+	l, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	squat := []strong.Exercise{{Name: "Squat (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 225, Reps: 5}}}}
+
+	matched := strong.Workout{Name: "Day A", Date: "2022-11-14T07:15:24Z", Duration: 30 * time.Minute, Exercises: squat}
+	edited := strong.Workout{Name: "Day B", Date: "2022-11-16T06:54:38Z", Duration: 45 * time.Minute, Exercises: squat}
+	added := strong.Workout{Name: "Day C", Date: "2022-11-18T06:30:00Z", Duration: 40 * time.Minute, Exercises: squat}
+	removed := strong.Workout{Name: "Day Z", Date: "2022-11-10T06:30:00Z", Duration: 40 * time.Minute}
+
+	server.AddActivity(stravatest.Activity{Name: "Day A", SportType: "WeightTraining", StartDateLocal: matched.Date, ElapsedTime: 1800})
+	dayB := server.AddActivity(stravatest.Activity{Name: "Day B", SportType: "WeightTraining", StartDateLocal: edited.Date, ElapsedTime: 2700})
+	dayZ := server.AddActivity(stravatest.Activity{Name: "Day Z", SportType: "WeightTraining", StartDateLocal: removed.Date, ElapsedTime: 2400})
+
+	uploaded := edited
+	uploaded.Exercises = nil
+
+	for _, record := range []struct {
+		workout strong.Workout
+		id      int64
+	}{{uploaded, dayB.ID}, {removed, dayZ.ID}} {
+		if err := l.RecordUpload(strava.Destination, record.workout.Key(), record.workout.ContentHash(), strconv.FormatInt(record.id, 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	provider := strava.NewProvider(log.New(io.Discard, "", 0), server.Client(), strava.WithBaseURL(server.URL), strava.WithLedger(l), strava.WithPrune(true))
+	workouts := []strong.Workout{matched, edited, added}
+
+	plan, err := provider.Plan(context.Background(), workouts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, request := range server.Requests() {
+		assert.True(t, strings.HasPrefix(request, "GET "), "plan should only read from strava, got %s", request)
+	}
+
+	assert.Equal(t, 1, plan.Unchanged)
+
+	actions := make([]string, 0, len(plan.Activities))
+	for _, planned := range plan.Activities {
+		actions = append(actions, string(planned.Action)+" "+planned.WorkoutKey)
+	}
+
+	assert.Equal(t, []string{"update " + edited.Key(), "create " + added.Key(), "delete " + removed.Key()}, actions)
+	assert.Equal(t, dayB.ID, plan.Activities[0].Activity.ID)
+	assert.Contains(t, plan.Activities[1].Activity.Description, "Squat (Barbell)")
+	assert.Contains(t, plan.String(), "create "+added.Key()+"\nDay C\n")
+
+	if err := provider.Apply(context.Background(), plan); err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0)
+	for _, activity := range server.Activities() {
+		names = append(names, activity.Name)
+	}
+
+	assert.Equal(t, []string{"Day A", "Day B", "Day C"}, names)
+
+	entry, _ := l.Lookup(strava.Destination, edited.Key())
+	assert.Equal(t, edited.ContentHash(), entry.ContentHash, "update should record the new content hash")
+
+	_, ok := l.Lookup(strava.Destination, removed.Key())
+	assert.False(t, ok, "deleted workout should leave the ledger")
+
+	plan, err = provider.Plan(context.Background(), workouts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, plan.Empty(), "applied plan should leave nothing to do, got %s", plan)
+}
+
+func TestProvider_ApplyRefusedDelete(t *testing.T) {
+	t.Parallel()
+
+	server := stravatest.NewServer()
+	defer server.Close()
+
+	l, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	added := strong.Workout{Name: "Day C", Date: "2022-11-18T06:30:00Z", Duration: 40 * time.Minute}
+	removed := strong.Workout{Name: "Day Z", Date: "2022-11-10T06:30:00Z", Duration: 40 * time.Minute}
+
+	dayZ := server.AddActivity(stravatest.Activity{Name: "Day Z", SportType: "WeightTraining", StartDateLocal: removed.Date, ElapsedTime: 2400})
+
+	if err := l.RecordUpload(strava.Destination, removed.Key(), removed.ContentHash(), strconv.FormatInt(dayZ.ID, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	server.FailNext(http.MethodDelete, "/activities/"+strconv.FormatInt(dayZ.ID, 10), http.StatusUnauthorized, "Authorization Error")
+
+	provider := strava.NewProvider(log.New(io.Discard, "", 0), server.Client(), strava.WithBaseURL(server.URL), strava.WithLedger(l), strava.WithPrune(true))
+
+	err = provider.UploadNewWorkouts(context.Background(), []strong.Workout{added})
+	assert.NoError(t, err, "refused delete should not fail the sync")
+
+	_, ok := l.Lookup(strava.Destination, added.Key())
+	assert.True(t, ok, "create should be recorded")
+
+	_, ok = l.Lookup(strava.Destination, removed.Key())
+	assert.True(t, ok, "refused delete should keep the ledger entry")
+}
+
+func TestProvider_PlanWithoutPrune(t *testing.T) {
+	t.Parallel()
+
+	server := stravatest.NewServer()
+	defer server.Close()
+
+	l, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.RecordUpload(strava.Destination, "20221110T063000", "hash", "7"); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := strava.NewProvider(log.New(io.Discard, "", 0), server.Client(), strava.WithBaseURL(server.URL), strava.WithLedger(l))
+
+	plan, err := provider.Plan(context.Background(), []strong.Workout{{Name: "Day A", Date: "2022-11-14T07:15:24Z", Duration: time.Hour}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, plan.Activities, 1)
+	assert.Equal(t, strava.ActionCreate, plan.Activities[0].Action)
+}
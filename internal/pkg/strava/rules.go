@@ -0,0 +1,129 @@
+package strava
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+const (
+	VisibilityEveryone      = "everyone"
+	VisibilityFollowersOnly = "followers_only"
+	VisibilityOnlyMe        = "only_me"
+)
+
+// ActivityRule sets activity options for workouts whose name matches Program.
+// Unset options leave the activity unchanged.
+type ActivityRule struct {
+	// Program is a regular expression matched against the workout name. An
+	// empty Program matches every workout.
+	Program      string `json:"program"`
+	Visibility   string `json:"visibility,omitempty"`
+	HideFromHome *bool  `json:"hide_from_home,omitempty"`
+	GearID       string `json:"gear_id,omitempty"`
+	Trainer      *bool  `json:"trainer,omitempty"`
+
+	program *regexp.Regexp
+}
+
+// ActivityRules are applied in order, so later rules override earlier ones.
+type ActivityRules []ActivityRule
+
+// LoadActivityRules reads a JSON array of ActivityRule from path.
+func LoadActivityRules(path string) (ActivityRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading activity rules %s: %w", path, err)
+	}
+
+	var rules ActivityRules
+
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("error unmarshling activity rules %s: %w", path, err)
+	}
+
+	if err := rules.compile(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (rules ActivityRules) compile() error {
+	for i := range rules {
+		switch rules[i].Visibility {
+		case "", VisibilityEveryone, VisibilityFollowersOnly, VisibilityOnlyMe:
+		default:
+			return fmt.Errorf("error activity rule %d has unknown visibility %q", i, rules[i].Visibility)
+		}
+
+		program, err := regexp.Compile(rules[i].Program)
+		if err != nil {
+			return fmt.Errorf("error activity rule %d program: %w", i, err)
+		}
+
+		rules[i].program = program
+	}
+
+	return nil
+}
+
+// Apply sets the options of every rule matching workout on activity.
+func (rules ActivityRules) Apply(workout strong.Workout, activity *Actvitiy) {
+	for _, rule := range rules {
+		if !rule.matches(workout.Name) {
+			continue
+		}
+
+		if rule.Visibility != "" {
+			activity.Visibility = rule.Visibility
+		}
+
+		if rule.HideFromHome != nil {
+			activity.HideFromHome = *rule.HideFromHome
+		}
+
+		if rule.GearID != "" {
+			activity.GearID = rule.GearID
+		}
+
+		if rule.Trainer != nil {
+			activity.Trainer = *rule.Trainer
+		}
+	}
+}
+
+// flags returns the flags the rules matching workout set explicitly, so an
+// update can clear them as well as set them.
+func (rules ActivityRules) flags(workout strong.Workout) activityFlags {
+	var flags activityFlags
+
+	for _, rule := range rules {
+		if !rule.matches(workout.Name) {
+			continue
+		}
+
+		if rule.HideFromHome != nil {
+			flags.HideFromHome = rule.HideFromHome
+		}
+
+		if rule.Trainer != nil {
+			flags.Trainer = rule.Trainer
+		}
+	}
+
+	return flags
+}
+
+func (rule ActivityRule) matches(name string) bool {
+	if rule.program != nil {
+		return rule.program.MatchString(name)
+	}
+
+	matched, err := regexp.MatchString(rule.Program, name)
+
+	return err == nil && matched
+}
@@ -0,0 +1,86 @@
+package strava_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adiazny/strong/internal/pkg/strava"
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActivityRules_Apply(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+
+	err := os.WriteFile(path, []byte(`[
+		{"program": "", "gear_id": "g1", "hide_from_home": true},
+		{"program": "^5/3/1", "visibility": "followers_only"},
+		{"program": "Deload", "visibility": "only_me", "hide_from_home": false}
+	]`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := strava.LoadActivityRules(path)
+	if err != nil {
+		t.Fatalf("LoadActivityRules() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		workout string
+		want    strava.Actvitiy
+	}{
+		{
+			name:    "default rule only",
+			workout: "Day A",
+			want:    strava.Actvitiy{GearID: "g1", HideFromHome: true},
+		},
+		{
+			name:    "program rule",
+			workout: "5/3/1 Squat Day",
+			want:    strava.Actvitiy{GearID: "g1", HideFromHome: true, Visibility: strava.VisibilityFollowersOnly},
+		},
+		{
+			name:    "later rule overrides",
+			workout: "5/3/1 Deload Week",
+			want:    strava.Actvitiy{GearID: "g1", HideFromHome: false, Visibility: strava.VisibilityOnlyMe},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got strava.Actvitiy
+
+			rules.Apply(strong.Workout{Name: tt.workout}, &got)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadActivityRules_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for name, content := range map[string]string{
+		"unknown visibility": `[{"program": "", "visibility": "friends"}]`,
+		"invalid program":    `[{"program": "(unclosed"}]`,
+		"invalid json":       `{`,
+	} {
+		path := filepath.Join(t.TempDir(), "rules.json")
+
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := strava.LoadActivityRules(path)
+		assert.Error(t, err, name)
+	}
+}
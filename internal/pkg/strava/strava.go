@@ -9,46 +9,247 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/adiazny/strong/internal/pkg/format"
 	"github.com/adiazny/strong/internal/pkg/strong"
 )
 
+var (
+	// ErrNoNewActivities is returned by UploadNewWorkouts when every workout
+	// already has a Strava activity.
+	ErrNoNewActivities = errors.New("no strava activities to post")
+	// ErrNotFound is returned when Strava responds 404, for example for an
+	// activity deleted on Strava.
+	ErrNotFound = errors.New("strava resource not found")
+)
+
 const (
 	stravaBaseURL    = "https://www.strava.com/api/v3"
 	activitiesPath   = "activities"
 	activitesPerPage = 200
 	athletePath      = "athlete"
 	weightTraining   = "WeightTraining"
+
+	// workoutDateSlack widens the "after" window because Strong dates are
+	// local times while Strava filters on the UTC start date.
+	workoutDateSlack = 24 * time.Hour
+
+	// cacheOverlap re-fetches recent cached activities so back-dated manual
+	// entries created since the last run are still seen.
+	cacheOverlap = 7 * 24 * time.Hour
 )
 
 type Provider struct {
 	log        *log.Logger
 	httpClient *http.Client
+	baseURL    string
+	cache      *ActivityCache
+	matcher    Matcher
+	formatter  *format.Formatter
+	rules      ActivityRules
+
+	encode             Encoder
+	uploadDataType     string
+	uploadPollInterval time.Duration
+	uploadTimeout      time.Duration
+
+	renderPhoto PhotoRenderer
+	ledger      Ledger
+	prune       bool
+
+	since      time.Time
+	until      time.Time
+	maxUploads int
+	pacing     time.Duration
+	backfill   bool
 }
 
-func NewProvider(log *log.Logger, httpClient *http.Client) *Provider {
-	return &Provider{log: log, httpClient: httpClient}
+// Option configures optional Provider behaviour.
+type Option func(*Provider)
+
+// WithBaseURL overrides the Strava API base URL, e.g. to point the provider
+// at a stravatest.Server.
+func WithBaseURL(baseURL string) Option {
+	return func(p *Provider) {
+		p.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithActivityCache keeps the last-seen activity list in cache so later runs
+// only fetch activities started since the newest cached one.
+func WithActivityCache(cache *ActivityCache) Option {
+	return func(p *Provider) {
+		p.cache = cache
+	}
+}
+
+// WithMatcher sets how workouts are matched against existing activities.
+func WithMatcher(matcher Matcher) Option {
+	return func(p *Provider) {
+		p.matcher = matcher
+	}
+}
+
+// WithFormatter renders activity names and descriptions with formatter
+// instead of MapStrongWorkout.
+func WithFormatter(formatter *format.Formatter) Option {
+	return func(p *Provider) {
+		p.formatter = formatter
+	}
+}
+
+// WithActivityRules applies rules to every activity before it is uploaded.
+func WithActivityRules(rules ActivityRules) Option {
+	return func(p *Provider) {
+		p.rules = rules
+	}
+}
+
+func NewProvider(log *log.Logger, httpClient *http.Client, opts ...Option) *Provider {
+	provider := &Provider{
+		log:                log,
+		httpClient:         httpClient,
+		baseURL:            stravaBaseURL,
+		matcher:            DefaultMatcher(),
+		uploadPollInterval: defaultUploadPollInterval,
+		uploadTimeout:      defaultUploadTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(provider)
+	}
+
+	return provider
 }
 
 type Actvitiy struct {
+	ID             int64   `json:"id,omitempty"`
 	Name           string  `json:"name"`
 	SportType      string  `json:"sport_type"`
+	StartDate      string  `json:"start_date,omitempty"`
 	StartDateLocal string  `json:"start_date_local"`
 	ElapsedTime    int     `json:"elapsed_time"`
 	Description    string  `json:"description,omitempty"`
 	Distance       float64 `json:"distance"`
 	Trainer        bool    `json:"trainer"`
 	Commute        bool    `json:"commute"`
+	HideFromHome   bool    `json:"hide_from_home,omitempty"`
+	GearID         string  `json:"gear_id,omitempty"`
+	Visibility     string  `json:"visibility,omitempty"`
+}
+
+// updatableActivity is the request body for PUT /activities/{id}. Flags are
+// only sent when set, so an update never clears one set on Strava or by an
+// earlier sync.
+type updatableActivity struct {
+	Name         string `json:"name"`
+	SportType    string `json:"sport_type"`
+	Description  string `json:"description"`
+	Trainer      *bool  `json:"trainer,omitempty"`
+	Commute      *bool  `json:"commute,omitempty"`
+	HideFromHome *bool  `json:"hide_from_home,omitempty"`
+	GearID       string `json:"gear_id,omitempty"`
+	Visibility   string `json:"visibility,omitempty"`
+}
+
+// activityFlags are the flags an update sends, nil for those left alone.
+type activityFlags struct {
+	Trainer      *bool
+	Commute      *bool
+	HideFromHome *bool
+}
+
+// setFlags returns the flags set on activity, leaving the others alone.
+func setFlags(activity Actvitiy) activityFlags {
+	return activityFlags{
+		Trainer:      setFlag(activity.Trainer),
+		Commute:      setFlag(activity.Commute),
+		HideFromHome: setFlag(activity.HideFromHome),
+	}
 }
 
-func (provider *Provider) GetActivities() ([]Actvitiy, error) {
-	allActivites := make([]Actvitiy, activitesPerPage)
+// override returns flags with the flags set in explicit replacing them.
+func (flags activityFlags) override(explicit activityFlags) activityFlags {
+	if explicit.Trainer != nil {
+		flags.Trainer = explicit.Trainer
+	}
+
+	if explicit.Commute != nil {
+		flags.Commute = explicit.Commute
+	}
+
+	if explicit.HideFromHome != nil {
+		flags.HideFromHome = explicit.HideFromHome
+	}
+
+	return flags
+}
+
+// setFlag returns a pointer to flag if it is set and nil otherwise.
+func setFlag(flag bool) *bool {
+	if !flag {
+		return nil
+	}
+
+	return &flag
+}
+
+func (activity Actvitiy) hasUpdateOnlyOptions() bool {
+	return activity.HideFromHome || activity.GearID != "" || activity.Visibility != ""
+}
+
+// GetActivities returns the athlete's WeightTraining activities started after
+// the given time. A zero time fetches the full history. When an ActivityCache
+// is configured only activities newer than the cached ones are requested.
+func (provider *Provider) GetActivities(ctx context.Context, after time.Time) ([]Actvitiy, error) {
+	var cached []Actvitiy
+
+	since, coveredFrom := after, after
+
+	if provider.cache != nil {
+		cachedActivities, cachedAfter, err := provider.cache.Load()
+
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			provider.log.Printf("ignoring strava activity cache: %v", err)
+		case cachedAfter.IsZero() || (!after.IsZero() && !after.Before(cachedAfter)):
+			cached, coveredFrom = cachedActivities, cachedAfter
+			since = latestStart(cached, after)
+		}
+	}
+
+	fetched, err := provider.fetchActivities(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	activities := filterActivities(mergeActivities(cached, fetched), func(activity Actvitiy) bool {
+		return activity.SportType == weightTraining
+	})
+
+	if provider.cache != nil {
+		if err := provider.cache.Save(coveredFrom, activities); err != nil {
+			provider.log.Printf("error saving strava activity cache: %v", err)
+		}
+	}
+
+	return filterActivities(activities, func(activity Actvitiy) bool {
+		return after.IsZero() || !activity.startTime().Before(after)
+	}), nil
+}
+
+func (provider *Provider) fetchActivities(ctx context.Context, after time.Time) ([]Actvitiy, error) {
+	allActivites := make([]Actvitiy, 0, activitesPerPage)
 
 	page := 1
 
 	for {
 		provider.log.Printf("processing strava athlete activities page %d", page)
-		activities, err := provider.getActivitiesPerPage(page)
+		activities, err := provider.getActivitiesPerPage(ctx, page, after)
 		if err != nil {
 			return nil, err
 		}
@@ -65,50 +266,90 @@ func (provider *Provider) GetActivities() ([]Actvitiy, error) {
 	return allActivites, nil
 }
 
-func (provider *Provider) PostActivity(activity Actvitiy) error {
-	activityData, err := json.Marshal(activity)
+// PostActivity creates a manual activity and then applies the options Strava
+// only accepts on update (visibility, hide_from_home and gear_id). It returns
+// the activity as stored by Strava. If only the update fails, the created
+// activity is returned with the error.
+func (provider *Provider) PostActivity(ctx context.Context, activity Actvitiy) (Actvitiy, error) {
+	var created Actvitiy
+
+	err := provider.doJSON(ctx, http.MethodPost, fmt.Sprintf("%s/%s", provider.baseURL, activitiesPath), activity, http.StatusCreated, &created)
 	if err != nil {
-		return fmt.Errorf("error marshling activity: %w", err)
+		return Actvitiy{}, err
 	}
 
-	bodyReader := bytes.NewReader(activityData)
+	if !activity.hasUpdateOnlyOptions() {
+		return created, nil
+	}
+
+	created.Visibility = activity.Visibility
+	created.HideFromHome = activity.HideFromHome
+	created.GearID = activity.GearID
 
-	resp, err := provider.httpClient.Post(fmt.Sprintf("%s/%s", stravaBaseURL, activitiesPath), "application/json", bodyReader)
+	updated, err := provider.UpdateActivity(ctx, created)
 	if err != nil {
-		return fmt.Errorf("error performing http post request: %w", err)
+		return created, fmt.Errorf("error updating created activity %d: %w", created.ID, err)
 	}
 
-	defer resp.Body.Close()
+	return updated, nil
+}
 
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("error response status code is %d", resp.StatusCode)
+// UpdateActivity replaces the updatable fields of the activity with the same
+// ID and returns the updated activity. Trainer, commute and hide_from_home
+// are only set, never cleared.
+func (provider *Provider) UpdateActivity(ctx context.Context, activity Actvitiy) (Actvitiy, error) {
+	return provider.updateActivity(ctx, activity, setFlags(activity))
+}
+
+func (provider *Provider) updateActivity(ctx context.Context, activity Actvitiy, flags activityFlags) (Actvitiy, error) {
+	if activity.ID == 0 {
+		return Actvitiy{}, errors.New("error activity id is required for update")
 	}
 
-	return nil
+	update := updatableActivity{
+		Name:         activity.Name,
+		SportType:    activity.SportType,
+		Description:  activity.Description,
+		Trainer:      flags.Trainer,
+		Commute:      flags.Commute,
+		HideFromHome: flags.HideFromHome,
+		GearID:       activity.GearID,
+		Visibility:   activity.Visibility,
+	}
+
+	var updated Actvitiy
+
+	err := provider.doJSON(ctx, http.MethodPut, fmt.Sprintf("%s/%s/%d", provider.baseURL, activitiesPath, activity.ID), update, http.StatusOK, &updated)
+	if err != nil {
+		return Actvitiy{}, err
+	}
+
+	return updated, nil
 }
 
+// UploadNewWorkouts plans a sync of workouts and applies it, returning
+// ErrNoNewActivities if there is nothing to change.
 func (provider *Provider) UploadNewWorkouts(ctx context.Context, workouts []strong.Workout) error {
-	stravaActivities, err := provider.GetActivities()
+	plan, err := provider.Plan(ctx, workouts)
 	if err != nil {
 		return err
 	}
 
-	newStrongWorkouts := filterNewWorkouts(stravaActivities, workouts)
-
-	newActivities := convertToStrava(newStrongWorkouts)
-
-	if len(newActivities) == 0 {
-		return errors.New("no strava activities to post")
+	if plan.Empty() {
+		return ErrNoNewActivities
 	}
 
-	for _, activity := range newActivities {
-		err := provider.PostActivity(activity)
-		if err != nil {
-			return fmt.Errorf("%v activity: %s and date %s", err, activity.Name, activity.StartDateLocal)
-		}
+	return provider.Apply(ctx, plan)
+}
+
+// createActivity creates activity for workout as a manual activity or, when
+// file uploads are configured, by uploading the encoded workout.
+func (provider *Provider) createActivity(ctx context.Context, workout strong.Workout, activity Actvitiy) (Actvitiy, error) {
+	if provider.encode != nil {
+		return provider.uploadActivity(ctx, workout, activity)
 	}
 
-	return nil
+	return provider.PostActivity(ctx, activity)
 }
 
 func MapStrongWorkout(workout strong.Workout) Actvitiy {
@@ -121,37 +362,124 @@ func MapStrongWorkout(workout strong.Workout) Actvitiy {
 	}
 }
 
-func filterNewWorkouts(activities []Actvitiy, workouts []strong.Workout) []strong.Workout {
-	stravaDateTimeMap := make(map[string]struct{})
-	newStrongWorkouts := make([]strong.Workout, 0)
+// convertToStrava maps workouts to activities tagged with their Marker. The
+// history is all known workouts, used by the formatter to detect PRs.
+func (provider *Provider) convertToStrava(workouts, history []strong.Workout) ([]Actvitiy, error) {
+	newActivities := make([]Actvitiy, 0)
+
+	for _, workout := range workouts {
+		activity := MapStrongWorkout(workout)
+
+		if provider.formatter != nil {
+			name, description, err := provider.formatter.Render(workout, history)
+			if err != nil {
+				return nil, fmt.Errorf("error formatting workout %s: %w", workout.Date, err)
+			}
+
+			activity.Name, activity.Description = name, description
+		}
+
+		activity.Description = fmt.Sprintf("%s\n%s", activity.Description, Marker(workout))
+
+		provider.rules.Apply(workout, &activity)
+
+		newActivities = append(newActivities, activity)
+	}
+
+	return newActivities, nil
+}
+
+// oldestWorkoutTime returns the earliest workout start, less workoutDateSlack,
+// or the zero time if any workout date cannot be parsed.
+func oldestWorkoutTime(workouts []strong.Workout) time.Time {
+	var oldest time.Time
+
+	for _, workout := range workouts {
+		start, err := workout.StartTime()
+		if err != nil {
+			return time.Time{}
+		}
+
+		if oldest.IsZero() || start.Before(oldest) {
+			oldest = start
+		}
+	}
+
+	if oldest.IsZero() {
+		return oldest
+	}
+
+	return oldest.Add(-workoutDateSlack)
+}
+
+func filterActivities(activities []Actvitiy, matchFunc func(activity Actvitiy) bool) []Actvitiy {
+	filtered := make([]Actvitiy, 0, len(activities))
 
 	for _, activity := range activities {
-		stravaDateTimeMap[activity.StartDateLocal] = struct{}{}
+		if matchFunc(activity) {
+			filtered = append(filtered, activity)
+		}
 	}
 
-	for _, strong := range workouts {
-		if _, found := stravaDateTimeMap[strong.Date]; !found {
-			newStrongWorkouts = append(newStrongWorkouts, strong)
+	return filtered
+}
+
+// mergeActivities combines cached and fetched activities, letting fetched
+// activities replace cached ones with the same ID.
+func mergeActivities(cached, fetched []Actvitiy) []Actvitiy {
+	merged := make([]Actvitiy, 0, len(cached)+len(fetched))
+	index := make(map[int64]int)
+
+	for _, activity := range append(cached, fetched...) {
+		if i, ok := index[activity.ID]; ok && activity.ID != 0 {
+			merged[i] = activity
+			continue
 		}
+
+		index[activity.ID] = len(merged)
+		merged = append(merged, activity)
 	}
 
-	return newStrongWorkouts
+	return merged
 }
 
-func convertToStrava(workouts []strong.Workout) []Actvitiy {
-	newActivities := make([]Actvitiy, 0)
+// latestStart returns the newest activity start less cacheOverlap, or after
+// if that is later.
+func latestStart(activities []Actvitiy, after time.Time) time.Time {
+	latest := after
 
-	for _, workout := range workouts {
-		activity := MapStrongWorkout(workout)
+	for _, activity := range activities {
+		if start := activity.startTime().Add(-cacheOverlap); start.After(latest) {
+			latest = start
+		}
+	}
 
-		newActivities = append(newActivities, activity)
+	return latest
+}
+
+func (activity Actvitiy) startTime() time.Time {
+	date := activity.StartDate
+	if date == "" {
+		date = activity.StartDateLocal
 	}
 
-	return newActivities
+	start, _ := time.Parse(time.RFC3339, date)
+
+	return start
 }
 
-func (provider *Provider) getActivitiesPerPage(page int) ([]Actvitiy, error) {
-	resp, err := provider.httpClient.Get(fmt.Sprintf("%s/%s/%s?per_page=%d&page=%d", stravaBaseURL, athletePath, activitiesPath, activitesPerPage, page))
+func (provider *Provider) getActivitiesPerPage(ctx context.Context, page int, after time.Time) ([]Actvitiy, error) {
+	url := fmt.Sprintf("%s/%s/%s?per_page=%d&page=%d", provider.baseURL, athletePath, activitiesPath, activitesPerPage, page)
+	if !after.IsZero() {
+		url = fmt.Sprintf("%s&after=%d", url, after.Unix())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating http get request: %w", err)
+	}
+
+	resp, err := provider.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error performing http get request: %w", err)
 	}
@@ -185,3 +513,59 @@ func (provider *Provider) getActivitiesPerPage(page int) ([]Actvitiy, error) {
 
 	return activities, nil
 }
+
+// doJSON sends body encoded as JSON, if not nil, and decodes the response into
+// out, if not nil, when the response status is wantStatus.
+func (provider *Provider) doJSON(ctx context.Context, method, url string, body any, wantStatus int, out any) error {
+	var bodyReader io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error marshling request body: %w", err)
+		}
+
+		bodyReader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return fmt.Errorf("error creating http %s request: %w", strings.ToLower(method), err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := provider.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error performing http %s request: %w", strings.ToLower(method), err)
+	}
+
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body %w", err)
+	}
+
+	if resp.StatusCode != wantStatus {
+		provider.log.Printf("%s\n", respBody)
+
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: response status code is %d", ErrNotFound, resp.StatusCode)
+		}
+
+		return fmt.Errorf("error response status code is %d", resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("error unmarshling response body %w", err)
+	}
+
+	return nil
+}
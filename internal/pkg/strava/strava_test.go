@@ -2,13 +2,19 @@ package strava_test
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/adiazny/strong/internal/pkg/strava"
+	"github.com/adiazny/strong/internal/pkg/strava/stravatest"
 	"github.com/adiazny/strong/internal/pkg/strong"
-	"golang.org/x/oauth2"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestMapStrongWorkout(t *testing.T) {
@@ -65,68 +71,265 @@ Set 3: 95.0# x 3
 }
 
 func TestProvider_PostActivity(t *testing.T) {
-	type fields struct {
-		Logger *log.Logger
-		Config *oauth2.Config
-	}
-	type args struct {
-		ctx      context.Context
-		token    *oauth2.Token
-		activity strava.Actvitiy
-	}
+	t.Parallel()
+
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		wantErr bool
+		name       string
+		activity   strava.Actvitiy
+		fail       int
+		failUpdate int
+		wantErr    bool
 	}{
-		// TODO: Add test cases.
+		{
+			name: "success",
+			activity: strava.Actvitiy{
+				Name:           "Day A",
+				SportType:      "WeightTraining",
+				StartDateLocal: "2022-11-14T07:15:24Z",
+				ElapsedTime:    1800,
+			},
+		},
+		{
+			name: "success with update only options",
+			activity: strava.Actvitiy{
+				Name:           "Day A",
+				SportType:      "WeightTraining",
+				StartDateLocal: "2022-11-14T07:15:24Z",
+				ElapsedTime:    1800,
+				HideFromHome:   true,
+				GearID:         "g12345",
+				Visibility:     strava.VisibilityFollowersOnly,
+			},
+		},
+		{
+			name: "missing required fields",
+			activity: strava.Actvitiy{
+				Name: "Day A",
+			},
+			wantErr: true,
+		},
+		{
+			name: "server error",
+			activity: strava.Actvitiy{
+				Name:           "Day A",
+				SportType:      "WeightTraining",
+				StartDateLocal: "2022-11-14T07:15:24Z",
+				ElapsedTime:    1800,
+			},
+			fail:    http.StatusInternalServerError,
+			wantErr: true,
+		},
+		{
+			name: "update error returns created activity",
+			activity: strava.Actvitiy{
+				Name:           "Day A",
+				SportType:      "WeightTraining",
+				StartDateLocal: "2022-11-14T07:15:24Z",
+				ElapsedTime:    1800,
+				HideFromHome:   true,
+			},
+			failUpdate: http.StatusInternalServerError,
+			wantErr:    true,
+		},
 	}
+
 	for _, tt := range tests {
+		tt := tt
+
 		t.Run(tt.name, func(t *testing.T) {
-			provider := &strava.Provider{
-				Logger: tt.fields.Logger,
-				Config: tt.fields.Config,
+			t.Parallel()
+
+			server := stravatest.NewServer()
+			defer server.Close()
+
+			if tt.fail != 0 {
+				server.FailNext(http.MethodPost, "/activities", tt.fail, "injected")
 			}
-			if err := provider.PostActivity(tt.args.ctx, tt.args.token, tt.args.activity); (err != nil) != tt.wantErr {
-				t.Errorf("Provider.PostActivity() error = %v, wantErr %v", err, tt.wantErr)
+
+			if tt.failUpdate != 0 {
+				server.FailNext(http.MethodPut, "/activities/1", tt.failUpdate, "injected")
 			}
+
+			provider := newTestProvider(server)
+
+			got, err := provider.PostActivity(context.Background(), tt.activity)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Provider.PostActivity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && tt.failUpdate != 0 {
+				assert.Equal(t, int64(1), got.ID, "created activity should be returned")
+				assert.Len(t, server.Activities(), 1)
+				return
+			}
+
+			if tt.wantErr {
+				assert.Empty(t, server.Activities())
+				return
+			}
+
+			activities := server.Activities()
+			assert.Len(t, activities, 1)
+			assert.Equal(t, activities[0].ID, got.ID)
+			assert.Equal(t, tt.activity.Name, activities[0].Name)
+			assert.Equal(t, tt.activity.StartDateLocal, activities[0].StartDateLocal)
+			assert.Equal(t, tt.activity.Visibility, activities[0].Visibility)
+			assert.Equal(t, tt.activity.HideFromHome, activities[0].HideFromHome)
+			assert.Equal(t, tt.activity.GearID, activities[0].GearID)
 		})
 	}
 }
 
 func TestProvider_GetActivities(t *testing.T) {
-	type fields struct {
-		Logger *log.Logger
-		Config *oauth2.Config
-	}
-	type args struct {
-		ctx   context.Context
-		token *oauth2.Token
-	}
+	t.Parallel()
+
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		want    []strava.Actvitiy
-		wantErr bool
+		name      string
+		seed      int
+		fail      int
+		wantPages int
+		wantErr   bool
 	}{
-		// TODO: Add test cases.
+		{
+			name:      "no activities",
+			seed:      0,
+			wantPages: 1,
+		},
+		{
+			name:      "single page",
+			seed:      3,
+			wantPages: 2,
+		},
+		{
+			name:      "multiple pages",
+			seed:      450,
+			wantPages: 4,
+		},
+		{
+			name:    "rate limited",
+			seed:    3,
+			fail:    http.StatusTooManyRequests,
+			wantErr: true,
+		},
 	}
+
 	for _, tt := range tests {
+		tt := tt
+
 		t.Run(tt.name, func(t *testing.T) {
-			provider := &strava.Provider{
-				Logger: tt.fields.Logger,
-				Config: tt.fields.Config,
+			t.Parallel()
+
+			server := stravatest.NewServer()
+			defer server.Close()
+
+			start := time.Date(2022, 11, 14, 7, 15, 24, 0, time.UTC)
+			for i := 0; i < tt.seed; i++ {
+				server.AddActivity(stravatest.Activity{
+					Name:           fmt.Sprintf("Workout %d", i),
+					SportType:      "WeightTraining",
+					StartDateLocal: start.AddDate(0, 0, i).Format(time.RFC3339),
+					ElapsedTime:    1800,
+				})
 			}
-			got, err := provider.GetActivities(tt.args.ctx, tt.args.token)
+
+			if tt.fail != 0 {
+				server.FailNext(http.MethodGet, "/athlete/activities", tt.fail, "injected")
+			}
+
+			provider := newTestProvider(server)
+
+			got, err := provider.GetActivities(context.Background(), time.Time{})
 			if (err != nil) != tt.wantErr {
-				t.Errorf("Provider.GetActivities() error = %v, wantErr %v", err, tt.wantErr)
-				return
+				t.Fatalf("Provider.GetActivities() error = %v, wantErr %v", err, tt.wantErr)
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("Provider.GetActivities() = %v, want %v", got, tt.want)
+
+			if tt.wantErr {
+				return
 			}
+
+			assert.Len(t, got, tt.seed)
+			assert.Len(t, server.Requests(), tt.wantPages)
 		})
 	}
 }
+
+func TestProvider_GetActivitiesAfter(t *testing.T) {
+	t.Parallel()
+
+	server := stravatest.NewServer()
+	defer server.Close()
+
+	server.AddActivity(stravatest.Activity{Name: "Old Lift", SportType: "WeightTraining", StartDateLocal: "2022-11-01T07:00:00Z", ElapsedTime: 1800})
+	server.AddActivity(stravatest.Activity{Name: "New Lift", SportType: "WeightTraining", StartDateLocal: "2022-11-20T07:00:00Z", ElapsedTime: 1800})
+	server.AddActivity(stravatest.Activity{Name: "New Run", SportType: "Run", StartDateLocal: "2022-11-21T07:00:00Z", ElapsedTime: 1800})
+
+	cache, err := strava.NewActivityCache(filepath.Join(t.TempDir(), "activities.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := strava.NewProvider(log.New(io.Discard, "", 0), server.Client(), strava.WithBaseURL(server.URL), strava.WithActivityCache(cache))
+
+	after := time.Date(2022, 11, 10, 0, 0, 0, 0, time.UTC)
+
+	got, err := provider.GetActivities(context.Background(), after)
+	if err != nil {
+		t.Fatalf("Provider.GetActivities() error = %v", err)
+	}
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, "New Lift", got[0].Name)
+	assert.Contains(t, server.Requests()[0], fmt.Sprintf("after=%d", after.Unix()))
+
+	server.AddActivity(stravatest.Activity{Name: "Newer Lift", SportType: "WeightTraining", StartDateLocal: "2022-12-20T07:00:00Z", ElapsedTime: 1800})
+
+	got, err = provider.GetActivities(context.Background(), after)
+	if err != nil {
+		t.Fatalf("Provider.GetActivities() error = %v", err)
+	}
+
+	assert.Len(t, got, 2)
+
+	// The second run only asks for activities near the newest cached one.
+	cachedAfter := time.Date(2022, 11, 13, 7, 0, 0, 0, time.UTC)
+	assert.Contains(t, server.Requests()[2], fmt.Sprintf("after=%d", cachedAfter.Unix()))
+}
+
+func TestProvider_UploadNewWorkouts(t *testing.T) {
+	t.Parallel()
+
+	server := stravatest.NewServer()
+	defer server.Close()
+
+	server.AddActivity(stravatest.Activity{
+		Name:           "Day A",
+		SportType:      "WeightTraining",
+		StartDateLocal: "2022-11-14T07:15:24Z",
+		ElapsedTime:    1800,
+	})
+
+	workouts := []strong.Workout{
+		{Name: "Day A", Date: "2022-11-14T07:15:24Z", Duration: 30 * time.Minute},
+		{Name: "Day B", Date: "2022-11-16T06:54:38Z", Duration: 45 * time.Minute},
+	}
+
+	provider := newTestProvider(server)
+
+	err := provider.UploadNewWorkouts(context.Background(), workouts)
+	if err != nil {
+		t.Fatalf("Provider.UploadNewWorkouts() error = %v", err)
+	}
+
+	activities := server.Activities()
+	assert.Len(t, activities, 2)
+	assert.Equal(t, "Day B", activities[1].Name)
+	assert.Equal(t, 2700, activities[1].ElapsedTime)
+
+	err = provider.UploadNewWorkouts(context.Background(), workouts)
+	assert.ErrorIs(t, err, strava.ErrNoNewActivities, "second upload should find nothing new to post")
+	assert.Len(t, server.Activities(), 2)
+}
+
+func newTestProvider(server *stravatest.Server) *strava.Provider {
+	return strava.NewProvider(log.New(io.Discard, "", 0), server.Client(), strava.WithBaseURL(server.URL))
+}
@@ -0,0 +1,594 @@
+// Package stravatest provides an in-memory fake of the subset of the Strava API
+// used by the strava package, so the upload flow can be tested offline.
+package stravatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	athleteActivitiesPath = "/athlete/activities"
+	activitiesPath        = "/activities"
+	uploadsPath           = "/uploads"
+	athletePath           = "/athlete"
+	athletesPath          = "/athletes"
+	photosSuffix          = "/photos"
+	defaultPerPage        = 30
+
+	rateLimitHeader = "X-RateLimit-Limit"
+	rateUsageHeader = "X-RateLimit-Usage"
+)
+
+// Activity is the fake server's representation of a Strava activity.
+type Activity struct {
+	ID             int64   `json:"id"`
+	Name           string  `json:"name"`
+	SportType      string  `json:"sport_type"`
+	StartDate      string  `json:"start_date"`
+	StartDateLocal string  `json:"start_date_local"`
+	ElapsedTime    int     `json:"elapsed_time"`
+	Description    string  `json:"description"`
+	Distance       float64 `json:"distance"`
+	Trainer        bool    `json:"trainer"`
+	Commute        bool    `json:"commute"`
+	HideFromHome   bool    `json:"hide_from_home"`
+	GearID         string  `json:"gear_id"`
+	Visibility     string  `json:"visibility"`
+}
+
+// Athlete is the fake server's authorized athlete.
+type Athlete struct {
+	ID                    int64   `json:"id"`
+	Username              string  `json:"username"`
+	Firstname             string  `json:"firstname"`
+	Lastname              string  `json:"lastname"`
+	MeasurementPreference string  `json:"measurement_preference"`
+	Weight                float64 `json:"weight"`
+}
+
+// Upload is a file received by the fake /uploads endpoint.
+type Upload struct {
+	ID          int64
+	Name        string
+	Description string
+	DataType    string
+	ExternalID  string
+	Data        []byte
+	ActivityID  int64
+	Error       string
+
+	polls int
+}
+
+// Photo is an image received by the fake activity photos endpoint.
+type Photo struct {
+	ActivityID  int64
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// UploadProcessor turns an uploaded file into the activity it creates.
+type UploadProcessor func(upload Upload) (Activity, error)
+
+type fault struct {
+	status int
+	body   string
+}
+
+// Server is an httptest.Server that behaves like the Strava API. The zero
+// rate limit disables 429 responses; rate limit headers are always sent.
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	activities map[int64]*Activity
+	nextID     int64
+	faults     map[string][]fault
+	requests   []string
+	rateLimit  int
+	usage      int
+
+	athlete       Athlete
+	uploads       map[int64]*Upload
+	photos        []Photo
+	uploadPolls   int
+	processUpload UploadProcessor
+}
+
+// NewServer starts a fake Strava server. Callers must Close it.
+func NewServer() *Server {
+	s := &Server{
+		activities:  make(map[int64]*Activity),
+		nextID:      1,
+		faults:      make(map[string][]fault),
+		athlete:     Athlete{ID: 1, Firstname: "Test", Lastname: "Athlete", MeasurementPreference: "feet"},
+		uploads:     make(map[int64]*Upload),
+		uploadPolls: 1,
+		processUpload: func(upload Upload) (Activity, error) {
+			return Activity{
+				Name:           upload.Name,
+				SportType:      "WeightTraining",
+				StartDateLocal: time.Now().UTC().Format(time.RFC3339),
+				Description:    upload.Description,
+			}, nil
+		},
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// AddActivity seeds an activity and returns it with its assigned ID.
+func (s *Server) AddActivity(activity Activity) Activity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return *s.insert(activity)
+}
+
+// Activities returns a snapshot of all stored activities ordered by ID.
+func (s *Server) Activities() []Activity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	activities := make([]Activity, 0, len(s.activities))
+	for _, activity := range s.activities {
+		activities = append(activities, *activity)
+	}
+
+	sort.Slice(activities, func(i, j int) bool {
+		return activities[i].ID < activities[j].ID
+	})
+
+	return activities
+}
+
+// Requests returns every request received as "METHOD /path?query".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+// FailNext makes the next request matching method and path respond with
+// status and body instead of being handled. Faults queue up in call order.
+func (s *Server) FailNext(method, path string, status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := method + " " + path
+	s.faults[key] = append(s.faults[key], fault{status: status, body: body})
+}
+
+// SetAthlete replaces the authorized athlete.
+func (s *Server) SetAthlete(athlete Athlete) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.athlete = athlete
+}
+
+// Uploads returns a snapshot of all files received by /uploads.
+func (s *Server) Uploads() []Upload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uploads := make([]Upload, 0, len(s.uploads))
+	for _, upload := range s.uploads {
+		uploads = append(uploads, *upload)
+	}
+
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].ID < uploads[j].ID
+	})
+
+	return uploads
+}
+
+// Photos returns all images attached to activities, in upload order.
+func (s *Server) Photos() []Photo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Photo(nil), s.photos...)
+}
+
+// SetUploadProcessing sets how many status polls an upload stays in progress
+// and how it is turned into an activity. A processor error is reported as
+// the upload's error. A nil processor keeps the current one.
+func (s *Server) SetUploadProcessing(polls int, processor UploadProcessor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.uploadPolls = polls
+
+	if processor != nil {
+		s.processUpload = processor
+	}
+}
+
+// SetRateLimit sets the short-term request limit. Requests past the limit
+// receive 429 Too Many Requests.
+func (s *Server) SetRateLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rateLimit = limit
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, strings.TrimSuffix(r.Method+" "+r.URL.RequestURI(), "?"))
+	s.usage++
+
+	w.Header().Set(rateLimitHeader, fmt.Sprintf("%d,%d", s.rateLimit, s.rateLimit*10))
+	w.Header().Set(rateUsageHeader, fmt.Sprintf("%d,%d", s.usage, s.usage))
+
+	if s.rateLimit > 0 && s.usage > s.rateLimit {
+		writeError(w, http.StatusTooManyRequests, "Rate Limit Exceeded")
+		return
+	}
+
+	key := r.Method + " " + r.URL.Path
+	if queued := s.faults[key]; len(queued) > 0 {
+		s.faults[key] = queued[1:]
+		writeError(w, queued[0].status, queued[0].body)
+		return
+	}
+
+	switch {
+	case r.URL.Path == athleteActivitiesPath && r.Method == http.MethodGet:
+		s.listActivities(w, r)
+	case r.URL.Path == activitiesPath && r.Method == http.MethodPost:
+		s.createActivity(w, r)
+	case strings.HasPrefix(r.URL.Path, activitiesPath+"/") && strings.HasSuffix(r.URL.Path, photosSuffix) && r.Method == http.MethodPost:
+		s.createPhoto(w, r)
+	case strings.HasPrefix(r.URL.Path, activitiesPath+"/"):
+		s.activity(w, r)
+	case r.URL.Path == athletePath && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.athlete)
+	case r.URL.Path == fmt.Sprintf("%s/%d/stats", athletesPath, s.athlete.ID) && r.Method == http.MethodGet:
+		s.athleteStats(w)
+	case r.URL.Path == uploadsPath && r.Method == http.MethodPost:
+		s.createUpload(w, r)
+	case strings.HasPrefix(r.URL.Path, uploadsPath+"/") && r.Method == http.MethodGet:
+		s.getUpload(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "Record Not Found")
+	}
+}
+
+func (s *Server) listActivities(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	perPage, err := intParam(query.Get("per_page"), defaultPerPage)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := intParam(query.Get("page"), 1)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	after, err := epochParam(query.Get("after"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	before, err := epochParam(query.Get("before"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	activities := make([]Activity, 0, len(s.activities))
+	for _, activity := range s.activities {
+		start, err := time.Parse(time.RFC3339, activity.StartDate)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if (!after.IsZero() && !start.After(after)) || (!before.IsZero() && !start.Before(before)) {
+			continue
+		}
+
+		activities = append(activities, *activity)
+	}
+
+	// Strava lists athlete activities newest first, or oldest first when
+	// filtering with after.
+	sort.Slice(activities, func(i, j int) bool {
+		if activities[i].StartDate == activities[j].StartDate {
+			return activities[i].ID > activities[j].ID
+		}
+
+		if !after.IsZero() {
+			return activities[i].StartDate < activities[j].StartDate
+		}
+
+		return activities[i].StartDate > activities[j].StartDate
+	})
+
+	start := (page - 1) * perPage
+	if start > len(activities) {
+		start = len(activities)
+	}
+
+	end := start + perPage
+	if end > len(activities) {
+		end = len(activities)
+	}
+
+	writeJSON(w, http.StatusOK, activities[start:end])
+}
+
+func (s *Server) createActivity(w http.ResponseWriter, r *http.Request) {
+	var activity Activity
+
+	if err := json.NewDecoder(r.Body).Decode(&activity); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if activity.Name == "" || activity.SportType == "" || activity.StartDateLocal == "" || activity.ElapsedTime == 0 {
+		writeError(w, http.StatusBadRequest, "name, sport_type, start_date_local and elapsed_time are required")
+		return
+	}
+
+	// Like Strava, options only accepted on update are ignored on create.
+	activity.HideFromHome, activity.GearID, activity.Visibility = false, "", ""
+
+	writeJSON(w, http.StatusCreated, s.insert(activity))
+}
+
+func (s *Server) activity(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, activitiesPath+"/"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Record Not Found")
+		return
+	}
+
+	activity, ok := s.activities[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Record Not Found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, activity)
+	case http.MethodPut:
+		// Decoding over the stored value applies only the fields present.
+		if err := json.NewDecoder(r.Body).Decode(activity); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		activity.ID = id
+
+		writeJSON(w, http.StatusOK, activity)
+	case http.MethodDelete:
+		delete(s.activities, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func (s *Server) createPhoto(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, activitiesPath+"/"), photosSuffix), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Record Not Found")
+		return
+	}
+
+	if _, ok := s.activities[id]; !ok {
+		writeError(w, http.StatusNotFound, "Record Not Found")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.photos = append(s.photos, Photo{
+		ActivityID:  id,
+		Name:        header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Data:        data,
+	})
+
+	writeJSON(w, http.StatusCreated, map[string]any{"activity_id": id, "unique_id": fmt.Sprintf("%d-%d", id, len(s.photos))})
+}
+
+// athleteStats totals the stored run activities; other totals are zero.
+func (s *Server) athleteStats(w http.ResponseWriter) {
+	var runs struct {
+		Count       int     `json:"count"`
+		Distance    float64 `json:"distance"`
+		ElapsedTime int     `json:"elapsed_time"`
+	}
+
+	for _, activity := range s.activities {
+		if activity.SportType == "Run" {
+			runs.Count++
+			runs.Distance += activity.Distance
+			runs.ElapsedTime += activity.ElapsedTime
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"all_run_totals": runs})
+}
+
+func (s *Server) createUpload(w http.ResponseWriter, r *http.Request) {
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dataType := r.FormValue("data_type")
+	if dataType == "" {
+		writeError(w, http.StatusBadRequest, "data_type is required")
+		return
+	}
+
+	upload := &Upload{
+		ID:          int64(len(s.uploads) + 1),
+		Name:        r.FormValue("name"),
+		Description: r.FormValue("description"),
+		DataType:    dataType,
+		ExternalID:  r.FormValue("external_id"),
+		Data:        data,
+	}
+
+	s.uploads[upload.ID] = upload
+
+	writeJSON(w, http.StatusCreated, uploadStatus(upload))
+}
+
+func (s *Server) getUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, uploadsPath+"/"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Record Not Found")
+		return
+	}
+
+	upload, ok := s.uploads[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Record Not Found")
+		return
+	}
+
+	upload.polls++
+
+	if upload.polls >= s.uploadPolls && upload.ActivityID == 0 && upload.Error == "" {
+		activity, err := s.processUpload(*upload)
+		if err != nil {
+			upload.Error = err.Error()
+		} else {
+			upload.ActivityID = s.insert(activity).ID
+		}
+	}
+
+	writeJSON(w, http.StatusOK, uploadStatus(upload))
+}
+
+func uploadStatus(upload *Upload) map[string]any {
+	status := "Your activity is still being processed."
+
+	switch {
+	case upload.Error != "":
+		status = "There was an error processing your activity."
+	case upload.ActivityID != 0:
+		status = "Your activity is ready."
+	}
+
+	var activityID any
+	if upload.ActivityID != 0 {
+		activityID = upload.ActivityID
+	}
+
+	var uploadError any
+	if upload.Error != "" {
+		uploadError = upload.Error
+	}
+
+	return map[string]any{
+		"id":          upload.ID,
+		"id_str":      strconv.FormatInt(upload.ID, 10),
+		"external_id": upload.ExternalID,
+		"error":       uploadError,
+		"status":      status,
+		"activity_id": activityID,
+	}
+}
+
+func (s *Server) insert(activity Activity) *Activity {
+	activity.ID = s.nextID
+	s.nextID++
+
+	if activity.StartDate == "" {
+		activity.StartDate = activity.StartDateLocal
+	}
+
+	s.activities[activity.ID] = &activity
+
+	return &activity
+}
+
+func intParam(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid parameter value %q", value)
+	}
+
+	return n, nil
+}
+
+func epochParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid epoch parameter value %q", value)
+	}
+
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"message": message,
+		"errors":  []any{},
+	})
+}
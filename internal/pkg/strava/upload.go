@@ -0,0 +1,229 @@
+package strava
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/strong"
+)
+
+const (
+	uploadsPath = "uploads"
+
+	defaultUploadPollInterval = 2 * time.Second
+	defaultUploadTimeout      = 2 * time.Minute
+)
+
+// Encoder converts a workout into an activity file accepted by /uploads.
+type Encoder func(workout strong.Workout) ([]byte, error)
+
+// Upload is the status of a file sent to /uploads.
+type Upload struct {
+	ID         int64  `json:"id"`
+	ExternalID string `json:"external_id"`
+	Error      string `json:"error"`
+	Status     string `json:"status"`
+	ActivityID int64  `json:"activity_id"`
+}
+
+// UploadFile is an activity file and the metadata sent along with it.
+type UploadFile struct {
+	Name        string
+	Description string
+	Trainer     bool
+	Commute     bool
+	// DataType is the file format: fit, tcx or gpx, optionally gzipped.
+	DataType   string
+	ExternalID string
+	Data       []byte
+}
+
+// WithFileUploads creates activities by sending files produced by encode to
+// /uploads instead of posting manual activities.
+func WithFileUploads(dataType string, encode Encoder) Option {
+	return func(p *Provider) {
+		p.uploadDataType = dataType
+		p.encode = encode
+	}
+}
+
+// WithUploadPolling sets how often and for how long upload status is polled.
+func WithUploadPolling(interval, timeout time.Duration) Option {
+	return func(p *Provider) {
+		p.uploadPollInterval = interval
+		p.uploadTimeout = timeout
+	}
+}
+
+// UploadActivityFile sends file to /uploads and returns the initial status.
+func (provider *Provider) UploadActivityFile(ctx context.Context, file UploadFile) (Upload, error) {
+	var body bytes.Buffer
+
+	writer := multipart.NewWriter(&body)
+
+	fields := map[string]string{
+		"name":        file.Name,
+		"description": file.Description,
+		"trainer":     boolField(file.Trainer),
+		"commute":     boolField(file.Commute),
+		"data_type":   file.DataType,
+		"external_id": file.ExternalID,
+	}
+
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return Upload{}, fmt.Errorf("error writing upload field %s: %w", name, err)
+		}
+	}
+
+	part, err := writer.CreateFormFile("file", file.ExternalID+"."+file.DataType)
+	if err != nil {
+		return Upload{}, fmt.Errorf("error creating upload file part: %w", err)
+	}
+
+	if _, err := part.Write(file.Data); err != nil {
+		return Upload{}, fmt.Errorf("error writing upload file part: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return Upload{}, fmt.Errorf("error closing upload body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/%s", provider.baseURL, uploadsPath), &body)
+	if err != nil {
+		return Upload{}, fmt.Errorf("error creating http post request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := provider.httpClient.Do(req)
+	if err != nil {
+		return Upload{}, fmt.Errorf("error performing http post request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Upload{}, fmt.Errorf("error reading response body %w", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		provider.log.Printf("%s\n", respBody)
+
+		return Upload{}, fmt.Errorf("error response status code is %d", resp.StatusCode)
+	}
+
+	var upload Upload
+
+	if err := json.Unmarshal(respBody, &upload); err != nil {
+		return Upload{}, fmt.Errorf("error unmarshling response body %w", err)
+	}
+
+	return upload, nil
+}
+
+// GetUpload returns the current status of an upload.
+func (provider *Provider) GetUpload(ctx context.Context, id int64) (Upload, error) {
+	var upload Upload
+
+	err := provider.doJSON(ctx, http.MethodGet, fmt.Sprintf("%s/%s/%d", provider.baseURL, uploadsPath, id), nil, http.StatusOK, &upload)
+	if err != nil {
+		return Upload{}, err
+	}
+
+	return upload, nil
+}
+
+// WaitForUpload polls the upload until Strava reports an activity ID or an
+// error, or the upload timeout expires.
+func (provider *Provider) WaitForUpload(ctx context.Context, upload Upload) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, provider.uploadTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(provider.uploadPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if upload.Error != "" {
+			return 0, fmt.Errorf("error processing upload %d: %s", upload.ID, upload.Error)
+		}
+
+		if upload.ActivityID != 0 {
+			return upload.ActivityID, nil
+		}
+
+		provider.log.Printf("strava upload %d: %s", upload.ID, upload.Status)
+
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("error waiting for upload %d: %w", upload.ID, ctx.Err())
+		case <-ticker.C:
+		}
+
+		var err error
+
+		upload, err = provider.GetUpload(ctx, upload.ID)
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// uploadActivity encodes workout, uploads it and waits for the activity.
+// Options Strava does not take from files are then applied with an update;
+// if only that fails, the created activity is returned with the error.
+func (provider *Provider) uploadActivity(ctx context.Context, workout strong.Workout, activity Actvitiy) (Actvitiy, error) {
+	if provider.encode == nil {
+		return Actvitiy{}, errors.New("error no upload encoder configured")
+	}
+
+	data, err := provider.encode(workout)
+	if err != nil {
+		return Actvitiy{}, fmt.Errorf("error encoding workout: %w", err)
+	}
+
+	upload, err := provider.UploadActivityFile(ctx, UploadFile{
+		Name:        activity.Name,
+		Description: activity.Description,
+		Trainer:     activity.Trainer,
+		Commute:     activity.Commute,
+		DataType:    provider.uploadDataType,
+		ExternalID:  Marker(workout),
+		Data:        data,
+	})
+	if err != nil {
+		return Actvitiy{}, err
+	}
+
+	activity.ID, err = provider.WaitForUpload(ctx, upload)
+	if err != nil {
+		return Actvitiy{}, err
+	}
+
+	if !activity.hasUpdateOnlyOptions() {
+		return activity, nil
+	}
+
+	updated, err := provider.UpdateActivity(ctx, activity)
+	if err != nil {
+		return activity, fmt.Errorf("error updating created activity %d: %w", activity.ID, err)
+	}
+
+	return updated, nil
+}
+
+func boolField(value bool) string {
+	if value {
+		return "1"
+	}
+
+	return "0"
+}
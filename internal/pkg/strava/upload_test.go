@@ -0,0 +1,99 @@
+package strava_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/fit"
+	"github.com/adiazny/strong/internal/pkg/strava"
+	"github.com/adiazny/strong/internal/pkg/strava/stravatest"
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvider_UploadNewWorkoutsFIT(t *testing.T) {
+	t.Parallel()
+
+	workout := strong.Workout{
+		Name:     "Day A",
+		Date:     "2022-11-14T07:15:24Z",
+		Duration: 30 * time.Minute,
+		Exercises: []strong.Exercise{
+			{Name: "Squat (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 135, Reps: 5}}},
+		},
+	}
+
+	encode := func(workout strong.Workout) ([]byte, error) {
+		return fit.Encode(workout, fit.Options{Unit: strong.Pounds})
+	}
+
+	tests := []struct {
+		name      string
+		processor stravatest.UploadProcessor
+		wantErr   bool
+	}{
+		{
+			name: "success",
+			processor: func(upload stravatest.Upload) (stravatest.Activity, error) {
+				return stravatest.Activity{Name: upload.Name, SportType: "WeightTraining", StartDateLocal: workout.Date, ElapsedTime: 1800}, nil
+			},
+		},
+		{
+			name: "processing error",
+			processor: func(upload stravatest.Upload) (stravatest.Activity, error) {
+				return stravatest.Activity{}, errors.New("duplicate of activity 1")
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := stravatest.NewServer()
+			defer server.Close()
+
+			server.SetUploadProcessing(3, tt.processor)
+
+			hide := true
+			rules := strava.ActivityRules{{HideFromHome: &hide}}
+
+			provider := strava.NewProvider(log.New(io.Discard, "", 0), server.Client(),
+				strava.WithBaseURL(server.URL),
+				strava.WithFileUploads("fit", encode),
+				strava.WithUploadPolling(time.Millisecond, time.Second),
+				strava.WithActivityRules(rules),
+			)
+
+			err := provider.UploadNewWorkouts(context.Background(), []strong.Workout{workout})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Provider.UploadNewWorkouts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			uploads := server.Uploads()
+			if assert.Len(t, uploads, 1) {
+				assert.Equal(t, "fit", uploads[0].DataType)
+				assert.Equal(t, "strong:20221114T071524", uploads[0].ExternalID)
+				assert.Equal(t, ".FIT", string(uploads[0].Data[8:12]))
+			}
+
+			if tt.wantErr {
+				assert.Empty(t, server.Activities())
+				return
+			}
+
+			activities := server.Activities()
+			if assert.Len(t, activities, 1) {
+				assert.Equal(t, "Day A", activities[0].Name)
+				assert.True(t, activities[0].HideFromHome, "options applied after upload")
+			}
+		})
+	}
+}
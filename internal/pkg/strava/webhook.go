@@ -0,0 +1,93 @@
+package strava
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const (
+	ObjectActivity = "activity"
+	ObjectAthlete  = "athlete"
+
+	AspectCreate = "create"
+	AspectUpdate = "update"
+	AspectDelete = "delete"
+)
+
+// WebhookEvent is a push subscription event sent by Strava.
+type WebhookEvent struct {
+	ObjectType     string            `json:"object_type"`
+	ObjectID       int64             `json:"object_id"`
+	AspectType     string            `json:"aspect_type"`
+	Updates        map[string]string `json:"updates"`
+	OwnerID        int64             `json:"owner_id"`
+	SubscriptionID int64             `json:"subscription_id"`
+	EventTime      int64             `json:"event_time"`
+}
+
+// Deauthorized reports whether the event revokes the athlete's authorization.
+func (event WebhookEvent) Deauthorized() bool {
+	return event.ObjectType == ObjectAthlete && event.Updates["authorized"] == "false"
+}
+
+// GetActivity returns the activity with the given ID.
+func (provider *Provider) GetActivity(ctx context.Context, id int64) (Actvitiy, error) {
+	var activity Actvitiy
+
+	err := provider.doJSON(ctx, http.MethodGet, fmt.Sprintf("%s/%s/%d", provider.baseURL, activitiesPath, id), nil, http.StatusOK, &activity)
+	if err != nil {
+		return Actvitiy{}, err
+	}
+
+	return activity, nil
+}
+
+// HandleWebhookEvent reconciles the activity cache and the ledger with an
+// activity event so the next sync sees deleted, edited and newly created
+// activities. Workouts whose activity was deleted are created again. Athlete
+// events are left to the caller.
+func (provider *Provider) HandleWebhookEvent(ctx context.Context, event WebhookEvent) error {
+	if event.ObjectType != ObjectActivity {
+		return nil
+	}
+
+	switch event.AspectType {
+	case AspectDelete:
+		provider.removeActivityRecord(event.ObjectID)
+
+		if provider.cache == nil {
+			return nil
+		}
+
+		return provider.cache.Modify(func(activities []Actvitiy) []Actvitiy {
+			return filterActivities(activities, func(activity Actvitiy) bool {
+				return activity.ID != event.ObjectID
+			})
+		})
+	case AspectCreate, AspectUpdate:
+		if provider.cache == nil {
+			return nil
+		}
+
+		activity, err := provider.GetActivity(ctx, event.ObjectID)
+		if err != nil {
+			return fmt.Errorf("error fetching activity %d: %w", event.ObjectID, err)
+		}
+
+		return provider.cache.Modify(func(activities []Actvitiy) []Actvitiy {
+			// Activities changed to another sport no longer count as matches.
+			activities = filterActivities(activities, func(cached Actvitiy) bool {
+				return cached.ID != activity.ID
+			})
+
+			if activity.SportType != weightTraining {
+				return activities
+			}
+
+			return append(activities, activity)
+		})
+	default:
+		return fmt.Errorf("error unknown webhook aspect type %q", event.AspectType)
+	}
+}
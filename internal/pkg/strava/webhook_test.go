@@ -0,0 +1,86 @@
+package strava_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/strava"
+	"github.com/adiazny/strong/internal/pkg/strava/stravatest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvider_HandleWebhookEvent(t *testing.T) {
+	t.Parallel()
+
+	server := stravatest.NewServer()
+	defer server.Close()
+
+	dayA := server.AddActivity(stravatest.Activity{Name: "Day A", SportType: "WeightTraining", StartDateLocal: "2022-11-14T07:15:24Z", ElapsedTime: 1800})
+	dayB := server.AddActivity(stravatest.Activity{Name: "Day B", SportType: "WeightTraining", StartDateLocal: "2022-11-16T07:15:24Z", ElapsedTime: 1800})
+
+	cache, err := strava.NewActivityCache(filepath.Join(t.TempDir(), "activities.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := strava.NewProvider(log.New(io.Discard, "", 0), server.Client(), strava.WithBaseURL(server.URL), strava.WithActivityCache(cache))
+
+	ctx := context.Background()
+
+	if _, err := provider.GetActivities(ctx, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	cachedNames := func() []string {
+		activities, _, err := cache.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := make([]string, 0, len(activities))
+		for _, activity := range activities {
+			names = append(names, activity.Name)
+		}
+
+		return names
+	}
+
+	assert.ElementsMatch(t, []string{"Day A", "Day B"}, cachedNames())
+
+	err = provider.HandleWebhookEvent(ctx, strava.WebhookEvent{ObjectType: "activity", AspectType: "delete", ObjectID: dayA.ID})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Day B"}, cachedNames())
+
+	created := server.AddActivity(stravatest.Activity{Name: "Manual Lift", SportType: "WeightTraining", StartDateLocal: "2021-01-01T07:00:00Z", ElapsedTime: 1800})
+
+	err = provider.HandleWebhookEvent(ctx, strava.WebhookEvent{ObjectType: "activity", AspectType: "create", ObjectID: created.ID})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Day B", "Manual Lift"}, cachedNames())
+
+	server.FailNext("GET", "/activities/999", 404, "Record Not Found")
+
+	err = provider.HandleWebhookEvent(ctx, strava.WebhookEvent{ObjectType: "activity", AspectType: "update", ObjectID: 999})
+	assert.Error(t, err)
+
+	// Changing the sport type takes the activity out of consideration.
+	_, err = provider.UpdateActivity(ctx, strava.Actvitiy{ID: dayB.ID, Name: "Day B Run", SportType: "Run"})
+	assert.NoError(t, err)
+
+	err = provider.HandleWebhookEvent(ctx, strava.WebhookEvent{ObjectType: "activity", AspectType: "update", ObjectID: dayB.ID, Updates: map[string]string{"type": "Run"}})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Manual Lift"}, cachedNames())
+
+	err = provider.HandleWebhookEvent(ctx, strava.WebhookEvent{ObjectType: "athlete", AspectType: "update", Updates: map[string]string{"authorized": "false"}})
+	assert.NoError(t, err, "athlete events are left to the caller")
+}
+
+func TestWebhookEvent_Deauthorized(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, strava.WebhookEvent{ObjectType: "athlete", Updates: map[string]string{"authorized": "false"}}.Deauthorized())
+	assert.False(t, strava.WebhookEvent{ObjectType: "activity", Updates: map[string]string{"title": "false"}}.Deauthorized())
+}
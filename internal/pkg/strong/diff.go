@@ -0,0 +1,282 @@
+package strong
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Change is how a workout or set differs between two exports.
+type Change string
+
+const (
+	Added    Change = "added"
+	Removed  Change = "removed"
+	Modified Change = "modified"
+)
+
+// FieldChange is one field whose value differs, formatted as text.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+func (change FieldChange) String() string {
+	return fmt.Sprintf("%s %q -> %q", change.Field, change.Old, change.New)
+}
+
+// SetChange is a set added, removed or edited within a workout. Sets are
+// identified by exercise name and Set Order.
+type SetChange struct {
+	Change   Change        `json:"change"`
+	Exercise string        `json:"exercise"`
+	Set      int           `json:"set"`
+	Fields   []FieldChange `json:"fields,omitempty"`
+}
+
+func (change SetChange) String() string {
+	prefix := fmt.Sprintf("%s set %d", change.Exercise, change.Set)
+
+	if change.Change != Modified {
+		return prefix + " " + string(change.Change)
+	}
+
+	fields := make([]string, 0, len(change.Fields))
+	for _, field := range change.Fields {
+		fields = append(fields, field.String())
+	}
+
+	return prefix + ": " + strings.Join(fields, ", ")
+}
+
+// WorkoutChange describes a workout present in both exports whose contents
+// differ.
+type WorkoutChange struct {
+	Key    string        `json:"key"`
+	Old    Workout       `json:"-"`
+	New    Workout       `json:"-"`
+	Fields []FieldChange `json:"fields,omitempty"`
+	Sets   []SetChange   `json:"sets,omitempty"`
+}
+
+// String lists the field and set changes on one line.
+func (change WorkoutChange) String() string {
+	changes := make([]string, 0, len(change.Fields)+len(change.Sets))
+
+	for _, field := range change.Fields {
+		changes = append(changes, field.String())
+	}
+
+	for _, set := range change.Sets {
+		changes = append(changes, set.String())
+	}
+
+	if len(changes) == 0 {
+		return "exercises reordered"
+	}
+
+	return strings.Join(changes, "; ")
+}
+
+// WorkoutDiff lists what changed between two exports. Workouts are matched
+// by Key and listed newest first.
+type WorkoutDiff struct {
+	Added    []Workout       `json:"added"`
+	Removed  []Workout       `json:"removed"`
+	Modified []WorkoutChange `json:"modified"`
+}
+
+// Diff compares the workouts of an export, before, with those of a newer
+// one, after.
+func Diff(before, after []Workout) WorkoutDiff {
+	oldByKey := make(map[string]Workout, len(before))
+	for _, workout := range before {
+		oldByKey[workout.Key()] = workout
+	}
+
+	newByKey := make(map[string]Workout, len(after))
+	for _, workout := range after {
+		newByKey[workout.Key()] = workout
+	}
+
+	diff := WorkoutDiff{Added: []Workout{}, Removed: []Workout{}, Modified: []WorkoutChange{}}
+
+	for key, workout := range newByKey {
+		previous, ok := oldByKey[key]
+
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, workout)
+		case previous.ContentHash() != workout.ContentHash():
+			diff.Modified = append(diff.Modified, diffWorkout(previous, workout))
+		}
+	}
+
+	for key, workout := range oldByKey {
+		if _, ok := newByKey[key]; !ok {
+			diff.Removed = append(diff.Removed, workout)
+		}
+	}
+
+	newestFirst := func(a, b Workout) int {
+		return cmp.Compare(b.Date, a.Date)
+	}
+
+	slices.SortFunc(diff.Added, newestFirst)
+	slices.SortFunc(diff.Removed, newestFirst)
+	slices.SortFunc(diff.Modified, func(a, b WorkoutChange) int {
+		return newestFirst(a.New, b.New)
+	})
+
+	return diff
+}
+
+// Empty reports whether the exports hold the same workouts.
+func (diff WorkoutDiff) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Modified) == 0
+}
+
+// Change returns the change of the workout with key, if it was modified.
+func (diff WorkoutDiff) Change(key string) (WorkoutChange, bool) {
+	for _, change := range diff.Modified {
+		if change.Key == key {
+			return change, true
+		}
+	}
+
+	return WorkoutChange{}, false
+}
+
+// String lists added (+), removed (-) and modified (~) workouts with their
+// field and set changes.
+func (diff WorkoutDiff) String() string {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "%d workouts added, %d removed, %d modified\n", len(diff.Added), len(diff.Removed), len(diff.Modified))
+
+	for _, workout := range diff.Added {
+		fmt.Fprintf(&builder, "+ %s %s\n", workout.Date, workout.Name)
+	}
+
+	for _, workout := range diff.Removed {
+		fmt.Fprintf(&builder, "- %s %s\n", workout.Date, workout.Name)
+	}
+
+	for _, change := range diff.Modified {
+		fmt.Fprintf(&builder, "~ %s %s\n", change.New.Date, change.New.Name)
+
+		for _, field := range change.Fields {
+			fmt.Fprintf(&builder, "    %s\n", field)
+		}
+
+		for _, set := range change.Sets {
+			fmt.Fprintf(&builder, "    %s\n", set)
+		}
+	}
+
+	return builder.String()
+}
+
+func diffWorkout(before, after Workout) WorkoutChange {
+	change := WorkoutChange{Key: after.Key(), Old: before, New: after}
+
+	change.Fields = appendFieldChange(change.Fields, "name", before.Name, after.Name)
+	change.Fields = appendFieldChange(change.Fields, "duration", before.Duration.String(), after.Duration.String())
+
+	oldSets := indexSets(before)
+	newSets := indexSets(after)
+
+	for _, set := range newSets {
+		previous, ok := findSet(oldSets, set.id)
+		if !ok {
+			change.Sets = append(change.Sets, SetChange{Change: Added, Exercise: set.id.exercise, Set: set.set.ID})
+			continue
+		}
+
+		if fields := diffSet(previous.set, set.set); len(fields) > 0 {
+			change.Sets = append(change.Sets, SetChange{Change: Modified, Exercise: set.id.exercise, Set: set.set.ID, Fields: fields})
+		}
+	}
+
+	for _, set := range oldSets {
+		if _, ok := findSet(newSets, set.id); !ok {
+			change.Sets = append(change.Sets, SetChange{Change: Removed, Exercise: set.id.exercise, Set: set.set.ID})
+		}
+	}
+
+	return change
+}
+
+func diffSet(before, after Set) []FieldChange {
+	var fields []FieldChange
+
+	fields = appendFieldChange(fields, "weight", formatFloat(before.Weight), formatFloat(after.Weight))
+	fields = appendFieldChange(fields, "reps", strconv.Itoa(before.Reps), strconv.Itoa(after.Reps))
+	fields = appendFieldChange(fields, "distance", formatFloat(before.Distance), formatFloat(after.Distance))
+	fields = appendFieldChange(fields, "duration", before.Duration.String(), after.Duration.String())
+	fields = appendFieldChange(fields, "notes", before.Notes, after.Notes)
+	fields = appendFieldChange(fields, "workout notes", before.WorkoutNotes, after.WorkoutNotes)
+	fields = appendFieldChange(fields, "rpe", formatFloat(before.RPE), formatFloat(after.RPE))
+
+	return fields
+}
+
+func appendFieldChange(fields []FieldChange, field, before, after string) []FieldChange {
+	if before == after {
+		return fields
+	}
+
+	return append(fields, FieldChange{Field: field, Old: before, New: after})
+}
+
+// setID identifies a set within a workout. Strong numbers sets per exercise,
+// so a repeated exercise and Set Order is told apart by occurrence.
+type setID struct {
+	exercise   string
+	set        int
+	occurrence int
+}
+
+type indexedSet struct {
+	id  setID
+	set Set
+}
+
+// indexSets flattens the sets of workout in export order. Assembled workouts
+// hold one exercise entry per exported row.
+func indexSets(workout Workout) []indexedSet {
+	seen := make(map[setID]int)
+	sets := make([]indexedSet, 0)
+
+	for _, exercise := range workout.Exercises {
+		for _, set := range exercise.Sets {
+			id := setID{exercise: exercise.Name, set: set.ID}
+
+			occurrence := seen[id]
+			seen[id]++
+
+			id.occurrence = occurrence
+
+			sets = append(sets, indexedSet{id: id, set: set})
+		}
+	}
+
+	return sets
+}
+
+func findSet(sets []indexedSet, id setID) (indexedSet, bool) {
+	for _, set := range sets {
+		if set.id == id {
+			return set, true
+		}
+	}
+
+	return indexedSet{}, false
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
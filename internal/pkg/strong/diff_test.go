@@ -0,0 +1,121 @@
+package strong_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adiazny/strong/internal/pkg/strong"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	squat := func(set int, weight float64, reps int) strong.Exercise {
+		return strong.Exercise{Name: "Squat (Barbell)", Sets: []strong.Set{{ID: set, Weight: weight, Reps: reps}}}
+	}
+
+	lowerA := strong.Workout{Name: "Lower A", Date: "2022-11-14T07:15:24Z", Duration: time.Hour, Exercises: []strong.Exercise{
+		squat(1, 75, 5), squat(2, 75, 5), squat(3, 75, 5),
+	}}
+	upperA := strong.Workout{Name: "Upper A", Date: "2022-11-16T06:54:38Z", Duration: time.Hour}
+	lowerB := strong.Workout{Name: "Lower B", Date: "2022-11-18T07:01:00Z", Duration: time.Hour}
+	upperB := strong.Workout{Name: "Upper B", Date: "2022-11-20T07:01:00Z", Duration: time.Hour}
+
+	lowerAEdited := lowerA
+	lowerAEdited.Name = "Lower A (edited)"
+	lowerAEdited.Exercises = []strong.Exercise{squat(1, 75, 5), squat(2, 80, 4), squat(4, 80, 5)}
+
+	tests := []struct {
+		name   string
+		before []strong.Workout
+		after  []strong.Workout
+		want   strong.WorkoutDiff
+	}{
+		{
+			name:   "unchanged",
+			before: []strong.Workout{lowerA, upperA},
+			after:  []strong.Workout{upperA, lowerA},
+			want:   strong.WorkoutDiff{Added: []strong.Workout{}, Removed: []strong.Workout{}, Modified: []strong.WorkoutChange{}},
+		},
+		{
+			name:   "added and removed newest first",
+			before: []strong.Workout{lowerA, upperA},
+			after:  []strong.Workout{lowerA, lowerB, upperB},
+			want: strong.WorkoutDiff{
+				Added:    []strong.Workout{upperB, lowerB},
+				Removed:  []strong.Workout{upperA},
+				Modified: []strong.WorkoutChange{},
+			},
+		},
+		{
+			name:   "modified with set changes",
+			before: []strong.Workout{lowerA},
+			after:  []strong.Workout{lowerAEdited},
+			want: strong.WorkoutDiff{
+				Added:   []strong.Workout{},
+				Removed: []strong.Workout{},
+				Modified: []strong.WorkoutChange{{
+					Key:    lowerA.Key(),
+					Old:    lowerA,
+					New:    lowerAEdited,
+					Fields: []strong.FieldChange{{Field: "name", Old: "Lower A", New: "Lower A (edited)"}},
+					Sets: []strong.SetChange{
+						{Change: strong.Modified, Exercise: "Squat (Barbell)", Set: 2, Fields: []strong.FieldChange{
+							{Field: "weight", Old: "75", New: "80"},
+							{Field: "reps", Old: "5", New: "4"},
+						}},
+						{Change: strong.Added, Exercise: "Squat (Barbell)", Set: 4},
+						{Change: strong.Removed, Exercise: "Squat (Barbell)", Set: 3},
+					},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := strong.Diff(tt.before, tt.after)
+
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, len(tt.want.Added)+len(tt.want.Removed)+len(tt.want.Modified) == 0, got.Empty())
+		})
+	}
+}
+
+func TestWorkoutDiff_String(t *testing.T) {
+	t.Parallel()
+
+	before := []strong.Workout{
+		{Name: "Lower A", Date: "2022-11-14T07:15:24Z", Duration: time.Hour, Exercises: []strong.Exercise{
+			{Name: "Squat (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 75, Reps: 5}}},
+		}},
+		{Name: "Upper A", Date: "2022-11-16T06:54:38Z", Duration: time.Hour},
+	}
+	after := []strong.Workout{
+		{Name: "Lower A", Date: "2022-11-14T07:15:24Z", Duration: 50 * time.Minute, Exercises: []strong.Exercise{
+			{Name: "Squat (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 77.5, Reps: 5, Notes: "belt"}}},
+		}},
+		{Name: "Lower B", Date: "2022-11-18T07:01:00Z", Duration: time.Hour},
+	}
+
+	diff := strong.Diff(before, after)
+
+	want := `1 workouts added, 1 removed, 1 modified
++ 2022-11-18T07:01:00Z Lower B
+- 2022-11-16T06:54:38Z Upper A
+~ 2022-11-14T07:15:24Z Lower A
+    duration "1h0m0s" -> "50m0s"
+    Squat (Barbell) set 1: weight "75" -> "77.5", notes "" -> "belt"
+`
+
+	assert.Equal(t, want, diff.String())
+
+	change, ok := diff.Change("20221114T071524")
+	assert.True(t, ok)
+	assert.Equal(t, "Lower A", change.New.Name)
+	assert.Equal(t, `duration "1h0m0s" -> "50m0s"; Squat (Barbell) set 1: weight "75" -> "77.5", notes "" -> "belt"`, change.String())
+}
@@ -2,7 +2,10 @@ package strong
 
 import (
 	"cmp"
+	"crypto/sha256"
 	"encoding/csv"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"slices"
@@ -12,6 +15,11 @@ import (
 	"time"
 )
 
+// exportColumns is the number of columns in a Strong CSV export.
+const exportColumns = 12
+
+var dateKeyReplacer = strings.NewReplacer("-", "", ":", "", "Z", "", " ", "T")
+
 type Config struct {
 	CompletedWorkouts []Workout
 }
@@ -24,6 +32,11 @@ type Workout struct {
 }
 
 func (workout *Workout) Description() string {
+	return workout.DescriptionIn(Pounds)
+}
+
+// DescriptionIn lists the workout sets with weights labelled in unit.
+func (workout *Workout) DescriptionIn(unit WeightUnit) string {
 	var stringBuilder strings.Builder
 
 	exercises := make(map[string]struct{})
@@ -35,7 +48,7 @@ func (workout *Workout) Description() string {
 		}
 
 		for _, set := range exercise.Sets {
-			fmt.Fprintf(&stringBuilder, "Set %d: %.1f# x %d", set.ID, set.Weight, set.Reps)
+			fmt.Fprintf(&stringBuilder, "Set %d: %.1f%s x %d", set.ID, set.Weight, unit.Symbol(), set.Reps)
 		}
 
 		stringBuilder.WriteString("\n")
@@ -44,11 +57,138 @@ func (workout *Workout) Description() string {
 	return stringBuilder.String()
 }
 
+// StartTime parses the workout Date as written by FormatDateTime. Strong
+// records local wall-clock time, so the returned time is only nominally UTC.
+func (workout *Workout) StartTime() (time.Time, error) {
+	return time.Parse(time.RFC3339, workout.Date)
+}
+
+// Key returns a stable identifier for the workout. Strong groups sets into a
+// workout by start date, so the key is the compacted Date.
+func (workout *Workout) Key() string {
+	return dateKeyReplacer.Replace(workout.Date)
+}
+
+// ContentHash returns a hex SHA-256 of the workout's contents, which changes
+// whenever the workout is edited in Strong.
+func (workout *Workout) ContentHash() string {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "%s\x00%s\x00%d\x00", workout.Name, workout.Date, workout.Duration)
+
+	for _, exercise := range workout.Exercises {
+		fmt.Fprintf(&builder, "%s\x00", exercise.Name)
+
+		for _, set := range exercise.Sets {
+			fmt.Fprintf(&builder, "%+v\x00", set)
+		}
+	}
+
+	sum := sha256.Sum256([]byte(builder.String()))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// WeightUnit is the unit weights were recorded in by the Strong app.
+type WeightUnit int
+
+const (
+	Pounds WeightUnit = iota
+	Kilograms
+
+	kilogramsPerPound  = 0.45359237
+	metersPerMile      = 1609.344
+	metersPerKilometer = 1000
+)
+
+// Symbol is the label printed after weights, "#" for pounds.
+func (unit WeightUnit) Symbol() string {
+	if unit == Kilograms {
+		return "kg"
+	}
+
+	return "#"
+}
+
+// Kilograms converts weight in unit to kilograms.
+func (unit WeightUnit) Kilograms(weight float64) float64 {
+	if unit == Pounds {
+		return weight * kilogramsPerPound
+	}
+
+	return weight
+}
+
+// FromKilograms converts a weight in kilograms to unit.
+func (unit WeightUnit) FromKilograms(weight float64) float64 {
+	if unit == Pounds {
+		return weight / kilogramsPerPound
+	}
+
+	return weight
+}
+
+// DistanceSymbol is the label printed after distances recorded alongside
+// weights in unit.
+func (unit WeightUnit) DistanceSymbol() string {
+	if unit == Pounds {
+		return "mi"
+	}
+
+	return "km"
+}
+
+// Meters converts a distance recorded alongside weights in unit to meters.
+// Strong records distances in miles when weights are in pounds and in
+// kilometres when they are in kilograms.
+func (unit WeightUnit) Meters(distance float64) float64 {
+	if unit == Pounds {
+		return distance * metersPerMile
+	}
+
+	return distance * metersPerKilometer
+}
+
 type Exercise struct {
 	Name string
 	Sets []Set
 }
 
+// bodyweightExercises are Strong exercises performed with bodyweight, for
+// which the recorded weight is added load or, if assisted, assistance.
+var bodyweightExercises = []string{"pull up", "chin up", "push up", "dip", "muscle up", "inverted row", "pistol squat"}
+
+// IsBodyweight reports whether the exercise moves the athlete's bodyweight.
+func (exercise Exercise) IsBodyweight() bool {
+	name := strings.ToLower(exercise.Name)
+
+	if strings.Contains(name, "(bodyweight)") || strings.Contains(name, "(assisted)") || strings.Contains(name, "(weighted)") {
+		return true
+	}
+
+	for _, bodyweight := range bodyweightExercises {
+		if strings.Contains(name, bodyweight) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Load returns the weight actually moved in a set of the exercise, counting
+// bodyweight for bodyweight exercises.
+func (exercise Exercise) Load(set Set, bodyweight float64) float64 {
+	if !exercise.IsBodyweight() {
+		return set.Weight
+	}
+
+	if strings.Contains(strings.ToLower(exercise.Name), "(assisted)") {
+		return max(bodyweight-set.Weight, 0)
+	}
+
+	return bodyweight + set.Weight
+}
+
 type Set struct {
 	ID           int
 	Weight       float64
@@ -79,69 +219,140 @@ func ExtractWorkouts(records [][]string) ([]Workout, error) {
 			continue
 		}
 
-		dateTime, err := FormatDateTime(record[0])
+		workout, err := extractWorkout(record)
 		if err != nil {
 			return nil, err
 		}
 
-		workoutDuration, err := parseWorkoutDuration(record[2])
-		if err != nil {
-			return nil, err
-		}
+		workouts = append(workouts, workout)
+	}
 
-		setID, err := strconv.Atoi(record[4])
-		if err != nil {
-			return nil, fmt.Errorf("error converting string to int for record index 4 %w", err)
-		}
+	return workouts, nil
+}
 
-		weight, err := parseFloat(record[5])
-		if err != nil {
-			return nil, err
+// Warning is a row of a Strong export that could not be read.
+type Warning struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+func (warning Warning) String() string {
+	return fmt.Sprintf("row %d: %s", warning.Row, warning.Message)
+}
+
+// ProcessLenient is Process for exports that may be damaged, such as ones
+// uploaded by hand. Rows that cannot be read are skipped and reported as
+// warnings, with rows numbered from 1 including the header. It only fails if
+// the file is not CSV at all or has no readable workouts.
+func ProcessLenient(file io.Reader) ([]Workout, []Warning, error) {
+	csvReader := csv.NewReader(file)
+	csvReader.FieldsPerRecord = -1
+
+	var (
+		rawWorkouts []Workout
+		warnings    []Warning
+	)
+
+	for row := 1; ; row++ {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
 		}
 
-		reps, err := strconv.Atoi(record[6])
-		if err != nil {
-			return nil, fmt.Errorf("error converting string to int for record index 6 %w", err)
+		var parseErr *csv.ParseError
+
+		if errors.As(err, &parseErr) {
+			warnings = append(warnings, Warning{Row: row, Message: parseErr.Err.Error()})
+			continue
 		}
 
-		distance, err := parseFloat(record[7])
 		if err != nil {
-			return nil, err
+			return nil, warnings, fmt.Errorf("error reading csv file %w", err)
 		}
 
-		setDuration, err := parseSetDuration(record[8])
-		if err != nil {
-			return nil, err
+		if row == 1 {
+			continue
 		}
 
-		rpe, err := parseFloat(record[11])
+		workout, err := extractWorkout(record)
 		if err != nil {
-			return nil, err
+			warnings = append(warnings, Warning{Row: row, Message: err.Error()})
+			continue
 		}
 
-		workout := Workout{
-			Name:     record[1],
-			Date:     dateTime,
-			Duration: workoutDuration,
-			Exercises: []Exercise{{
-				Name: record[3],
-				Sets: []Set{{
-					ID:           setID,
-					Weight:       weight,
-					Reps:         reps,
-					Distance:     distance,
-					Duration:     setDuration,
-					Notes:        record[9],
-					WorkoutNotes: record[10],
-					RPE:          rpe,
-				}},
-			}},
-		}
+		rawWorkouts = append(rawWorkouts, workout)
+	}
 
-		workouts = append(workouts, workout)
+	if len(rawWorkouts) == 0 {
+		return nil, warnings, errors.New("error no workouts found in csv file")
 	}
 
-	return workouts, nil
+	return AssembleWorkouts(rawWorkouts), warnings, nil
+}
+
+func extractWorkout(record []string) (Workout, error) {
+	if len(record) < exportColumns {
+		return Workout{}, fmt.Errorf("error expected %d columns, got %d", exportColumns, len(record))
+	}
+
+	dateTime, err := FormatDateTime(record[0])
+	if err != nil {
+		return Workout{}, err
+	}
+
+	workoutDuration, err := parseWorkoutDuration(record[2])
+	if err != nil {
+		return Workout{}, err
+	}
+
+	setID, err := strconv.Atoi(record[4])
+	if err != nil {
+		return Workout{}, fmt.Errorf("error converting string to int for record index 4 %w", err)
+	}
+
+	weight, err := parseFloat(record[5])
+	if err != nil {
+		return Workout{}, err
+	}
+
+	reps, err := strconv.Atoi(record[6])
+	if err != nil {
+		return Workout{}, fmt.Errorf("error converting string to int for record index 6 %w", err)
+	}
+
+	distance, err := parseFloat(record[7])
+	if err != nil {
+		return Workout{}, err
+	}
+
+	setDuration, err := parseSetDuration(record[8])
+	if err != nil {
+		return Workout{}, err
+	}
+
+	rpe, err := parseFloat(record[11])
+	if err != nil {
+		return Workout{}, err
+	}
+
+	return Workout{
+		Name:     record[1],
+		Date:     dateTime,
+		Duration: workoutDuration,
+		Exercises: []Exercise{{
+			Name: record[3],
+			Sets: []Set{{
+				ID:           setID,
+				Weight:       weight,
+				Reps:         reps,
+				Distance:     distance,
+				Duration:     setDuration,
+				Notes:        record[9],
+				WorkoutNotes: record[10],
+				RPE:          rpe,
+			}},
+		}},
+	}, nil
 }
 
 func AssembleWorkouts(workouts []Workout) []Workout {
@@ -230,6 +441,30 @@ func parseWorkoutDuration(duration string) (time.Duration, error) {
 	}
 }
 
+// Merge combines the workouts of several exports into one history, newest
+// first, keeping one workout per Key. Later exports win, so pass them oldest
+// first to keep edits made in the Strong app.
+func Merge(exports ...[]Workout) []Workout {
+	byKey := make(map[string]Workout)
+
+	for _, workouts := range exports {
+		for _, workout := range workouts {
+			byKey[workout.Key()] = workout
+		}
+	}
+
+	merged := make([]Workout, 0, len(byKey))
+	for _, workout := range byKey {
+		merged = append(merged, workout)
+	}
+
+	slices.SortFunc(merged, func(a, b Workout) int {
+		return cmp.Compare(b.Date, a.Date)
+	})
+
+	return merged
+}
+
 func FilterWorkouts(workouts []Workout, matchFunc func(workout Workout) bool) []Workout {
 	filteredWorkouts := make([]Workout, 0)
 
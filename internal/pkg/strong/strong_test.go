@@ -566,6 +566,124 @@ func TestGetLatestWorkout(t *testing.T) {
 	}
 }
 
+func TestProcessLenient(t *testing.T) {
+	t.Parallel()
+
+	header := "Date,Workout Name,Duration,Exercise Name,Set Order,Weight,Reps,Distance,Seconds,Notes,Workout Notes,RPE\n"
+	squat := "2022-11-14 07:15:24,Beginner A,30m,Squat (Barbell),1,75,5,0,0,,,\n"
+
+	tests := []struct {
+		name         string
+		csv          string
+		wantWorkouts int
+		wantWarnings []strong.Warning
+		wantErr      bool
+	}{
+		{
+			name:         "clean export",
+			csv:          header + squat,
+			wantWorkouts: 1,
+		},
+		{
+			name: "bad rows skipped",
+			csv: header + squat +
+				"2022-11-14 07:15:24,Beginner A,30m,Bench Press (Barbell),one,75,5,0,0,,,\n" +
+				"2022-11-16 06:54:38,Beginner B,45m,Deadlift\n" +
+				"2022-11-16 06:54:38,Beginner B,45m,Deadlift (Barbell),1,100,5,0,0,,,\n",
+			wantWorkouts: 2,
+			wantWarnings: []strong.Warning{
+				{Row: 3, Message: `error converting string to int for record index 4 strconv.Atoi: parsing "one": invalid syntax`},
+				{Row: 4, Message: "error expected 12 columns, got 4"},
+			},
+		},
+		{
+			name:    "header only",
+			csv:     header,
+			wantErr: true,
+		},
+		{
+			name: "no readable rows",
+			csv:  header + "yesterday,Beginner A,30m,Squat (Barbell),1,75,5,0,0,,,\n",
+			wantWarnings: []strong.Warning{
+				{Row: 2, Message: `parsing time "yesterday" as "2006-01-02 15:04:05": cannot parse "yesterday" as "2006"`},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			workouts, warnings, err := strong.ProcessLenient(bytes.NewBufferString(tt.csv))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Len(t, workouts, tt.wantWorkouts)
+			assert.Equal(t, tt.wantWarnings, warnings)
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	older := []strong.Workout{
+		{Name: "Lower A", Date: "2022-11-14T07:15:24Z", Duration: time.Hour},
+		{Name: "Upper A", Date: "2022-11-16T06:54:38Z", Duration: time.Hour},
+	}
+	newer := []strong.Workout{
+		{Name: "Upper A (edited)", Date: "2022-11-16T06:54:38Z", Duration: time.Hour},
+		{Name: "Lower B", Date: "2022-11-18T07:01:00Z", Duration: time.Hour},
+	}
+
+	tests := []struct {
+		name    string
+		exports [][]strong.Workout
+		want    []string
+	}{
+		{
+			name: "no exports",
+			want: []string{},
+		},
+		{
+			name:    "single export",
+			exports: [][]strong.Workout{older},
+			want:    []string{"Upper A", "Lower A"},
+		},
+		{
+			name:    "later export wins",
+			exports: [][]strong.Workout{older, newer},
+			want:    []string{"Lower B", "Upper A (edited)", "Lower A"},
+		},
+		{
+			name:    "earlier export loses",
+			exports: [][]strong.Workout{newer, older},
+			want:    []string{"Lower B", "Upper A", "Lower A"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			names := make([]string, 0)
+			for _, workout := range strong.Merge(tt.exports...) {
+				names = append(names, workout.Name)
+			}
+
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("Merge() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
 func TestWorkout_Description(t *testing.T) {
 	type fields struct {
 		Name      string
@@ -701,3 +819,21 @@ func TestFormatDateTime(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkout_ContentHash(t *testing.T) {
+	t.Parallel()
+
+	workout := strong.Workout{
+		Name:      "Day A",
+		Date:      "2022-11-14T07:15:24Z",
+		Duration:  time.Hour,
+		Exercises: []strong.Exercise{{Name: "Squat (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 225, Reps: 5}}}},
+	}
+
+	edited := workout
+	edited.Exercises = []strong.Exercise{{Name: "Squat (Barbell)", Sets: []strong.Set{{ID: 1, Weight: 225, Reps: 6}}}}
+
+	assert.Len(t, workout.ContentHash(), 64)
+	assert.Equal(t, workout.ContentHash(), workout.ContentHash())
+	assert.NotEqual(t, workout.ContentHash(), edited.ContentHash())
+}